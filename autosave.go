@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+
+	"biehdc.priv.aidatasetmanager/dataset"
+)
+
+// how often unsaved changes are written to the recovery journal
+const autosaveinterval = 30 * time.Second
+
+// settitle shows the project name and a * if there are unsaved changes
+func (g *gui) settitle(p *projectStructure) {
+	dirty := ""
+	if p.history.Dirty() {
+		dirty = "*"
+	}
+	g.w.SetTitle(fmt.Sprintf("%s%s - %s", dirty, filepath.Base(p.Source), apptitle))
+}
+
+// autosave keeps the recovery journal in sync with the unsaved changes until stop is called.
+// stop waits for a journal that is being written.
+func (g *gui) autosave(p *projectStructure) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(autosaveinterval)
+		defer ticker.Stop()
+		var written uint64 // version in the journal, 0 is nothing written
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			version := p.history.Version()
+			if !p.history.Dirty() {
+				if written != 0 {
+					// back to what is on disk
+					err := dataset.RemoveJournal(p.Source)
+					if err != nil {
+						fyne.LogError("could not remove recovery journal", err)
+					}
+					written = 0
+				}
+				continue
+			}
+			if version == written {
+				continue
+			}
+
+			var j *dataset.Journal
+			p.history.Read(func(dp *dataset.Project) {
+				j = dataset.NewJournal(dp)
+			})
+			err := dataset.WriteJournal(j)
+			if err != nil {
+				fyne.LogError("could not write recovery journal", err)
+				continue
+			}
+			written = version
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// offerrecovery asks to restore what was left behind by a crash
+func (g *gui) offerrecovery(p *projectStructure, restored func()) {
+	j, err := dataset.ReadJournal(p.Source)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			fyne.LogError("could not read recovery journal", err)
+		}
+		return
+	}
+
+	msg := fmt.Sprintf("There are unsaved changes from %s.\nDo you want to restore them?", j.Time.Format(time.DateTime))
+	dialog.ShowConfirm("Restore Changes", msg, func(b bool) {
+		if !b {
+			dataset.RemoveJournal(p.Source)
+			return
+		}
+		err := j.Restore(p.history)
+		if err != nil {
+			dialog.ShowError(err, g.w)
+		}
+		restored()
+	}, g.w)
+}
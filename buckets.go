@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"biehdc.priv.aidatasetmanager/dataset"
+)
+
+// showbuckets simulates aspect ratio bucketing and shows how full every bucket gets
+// and which images would be upscaled or lose a lot to cropping
+func (g *gui) showbuckets(p *projectStructure) {
+	prefs := g.a.Preferences()
+
+	resolution := widget.NewEntry()
+	resolution.SetText(strconv.Itoa(prefs.IntWithFallback("bucketresolution", dataset.DefaultBucketOptions.Resolution)))
+	step := widget.NewEntry()
+	step.SetText(strconv.Itoa(prefs.IntWithFallback("bucketstep", dataset.DefaultBucketOptions.Step)))
+	croplabel := widget.NewLabel("")
+	crop := widget.NewSlider(0, 50)
+	crop.Step = 1
+
+	progress := widget.NewProgressBar()
+	progress.Max = float64(len(p.Entries))
+	status := widget.NewLabel("Reading image sizes")
+
+	// the sizes are read in the background, which then sorts everything into buckets too
+	var mu sync.Mutex
+	var counts []dataset.BucketCount
+	var heavy []int
+	var fits []dataset.BucketFit
+	sizesread := false
+	bucketlist := widget.NewList(
+		func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(counts)
+		},
+		func() fyne.CanvasObject { return widget.NewLabel("0000x0000  1.00  00000 images") },
+		func(lii widget.ListItemID, co fyne.CanvasObject) {
+			mu.Lock()
+			c := counts[lii]
+			mu.Unlock()
+			co.(*widget.Label).SetText(fmt.Sprintf("%dx%d  %.2f  %d images", c.Width, c.Height, float64(c.Width)/float64(c.Height), len(c.Indexes)))
+		},
+	)
+	heavylist := widget.NewList(
+		func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(heavy)
+		},
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("averagefilename.len")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(lii widget.ListItemID, co fyne.CanvasObject) {
+			mu.Lock()
+			id := heavy[lii]
+			fit := fits[id]
+			mu.Unlock()
+			e := &p.Entries[id]
+			width, height := e.CropSize()
+			co.(*widget.Label).SetText(fmt.Sprintf("%s  %dx%d -> %dx%d, %.0f%% cropped, scaled %.2fx",
+				p.RelPath(e), width, height, fit.Width, fit.Height, fit.Crop*100, fit.Scale))
+		},
+	)
+
+	update := func() {
+		mu.Lock()
+		ready := sizesread
+		mu.Unlock()
+		if !ready {
+			return
+		}
+		opts := dataset.DefaultBucketOptions
+		res, err1 := strconv.Atoi(resolution.Text)
+		st, err2 := strconv.Atoi(step.Text)
+		if err1 != nil || err2 != nil || res < 64 || st < 1 {
+			status.SetText("Resolution and step have to be numbers")
+			return
+		}
+		prefs.SetInt("bucketresolution", res)
+		prefs.SetInt("bucketstep", st)
+		opts.Resolution = res
+		opts.Step = st
+		opts.MaxSize = max(opts.MaxSize, res*2)
+
+		var newfits []dataset.BucketFit
+		var newcounts []dataset.BucketCount
+		p.history.Read(func(*dataset.Project) {
+			newfits, newcounts = p.FitBuckets(opts)
+		})
+		var newheavy []int
+		for i, fit := range newfits {
+			if fit.Scale > 1 || fit.Crop > crop.Value/100 {
+				newheavy = append(newheavy, i)
+			}
+		}
+		mu.Lock()
+		fits, counts, heavy = newfits, newcounts, newheavy
+		mu.Unlock()
+		status.SetText(fmt.Sprintf("%d buckets used, %d images upscaled or cropped by more than %.0f%%", len(newcounts), len(newheavy), crop.Value))
+		bucketlist.Refresh()
+		heavylist.Refresh()
+	}
+	resolution.OnChanged = func(string) { update() }
+	step.OnChanged = func(string) { update() }
+	crop.OnChanged = func(f float64) {
+		croplabel.SetText(fmt.Sprintf("%0.0f%%", f))
+	}
+	crop.OnChangeEnded = func(f float64) {
+		prefs.SetInt("bucketcrop", int(f))
+		update()
+	}
+	crop.SetValue(float64(prefs.IntWithFallback("bucketcrop", 10)))
+
+	form := widget.NewForm(
+		widget.NewFormItem("Resolution", resolution),
+		widget.NewFormItem("Step", step),
+		widget.NewFormItem("Heavy crop", container.NewBorder(nil, nil, nil, croplabel, crop)),
+	)
+	results := container.NewHSplit(
+		container.NewBorder(widget.NewLabel("Buckets"), nil, nil, nil, bucketlist),
+		container.NewBorder(widget.NewLabel("Upscaled or heavily cropped"), nil, nil, nil, heavylist),
+	)
+	results.SetOffset(0.3)
+	content := container.NewBorder(container.NewVBox(form, progress, status), nil, nil, nil, results)
+	ctx, cancel := context.WithCancel(context.Background())
+	d := dialog.NewCustom("Buckets", "Close", content, g.w)
+	d.SetOnClosed(cancel)
+	d.Show()
+	d.Resize(g.w.Canvas().Size().Subtract(fyne.NewSquareSize(100)))
+
+	// the header is enough, so this is quick even without the thumbnails
+	go func() {
+		var problems int
+		for i := range len(p.Entries) {
+			if ctx.Err() != nil {
+				// closed before all sizes were read
+				return
+			}
+			p.history.Read(func(project *dataset.Project) {
+				if i >= len(project.Entries) {
+					return
+				}
+				e := &project.Entries[i]
+				if e.Width < 1 && e.ReadSize() != nil {
+					problems++
+				}
+			})
+			progress.SetValue(float64(i + 1))
+		}
+		progress.Hide()
+		mu.Lock()
+		sizesread = true
+		mu.Unlock()
+		update()
+		if problems > 0 {
+			status.SetText(status.Text + fmt.Sprintf(", %d images could not be read", problems))
+		}
+	}()
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"biehdc.priv.aidatasetmanager/dataset"
+)
+
+const cliusage = `usage: aidatasetmanager [command] [flags] <folder|file.jsonl>
+
+Without a command the gui is started, a folder or .jsonl file given to it is opened right away.
+
+commands:
+  convert   save the dataset as .txt files, a .jsonl file or a metadata.jsonl
+  validate  check that every image can be decoded
+  stats     print image and tag counts
+  export    write a resized and cropped copy of the dataset for training
+  lint      find images that are too small, blurry or badly compressed
+
+Run "aidatasetmanager <command> -h" for the flags of a command.
+`
+
+// iscommand reports if arg is one of the headless commands, anything else is left to the gui
+func iscommand(arg string) bool {
+	switch arg {
+	case "convert", "validate", "stats", "export", "lint", "help", "-h", "-help", "--help":
+		return true
+	}
+	return false
+}
+
+// runcli runs a headless command and returns the exit code
+func runcli(args []string) int {
+	switch args[0] {
+	case "convert":
+		return cliconvert(args[1:])
+	case "validate":
+		return clivalidate(args[1:])
+	case "stats":
+		return clistats(args[1:])
+	case "export":
+		return cliexport(args[1:])
+	case "lint":
+		return clilint(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(os.Stdout, cliusage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n%s", args[0], cliusage)
+		return 2
+	}
+}
+
+func newflagset(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: aidatasetmanager %s [flags] <folder|file.jsonl>\n\n%s\n\nflags:\n", name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseandload parses the flags and loads the one positional argument.
+// a code >= 0 means the command is done and should exit with it.
+func parseandload(fs *flag.FlagSet, args []string) (*dataset.Project, int) {
+	recursive := fs.Bool("r", false, "also load the images in subfolders")
+	images := fs.String("images", "", "extensions that count as images (default \""+strings.Join(dataset.DefaultImageExtensions, " ")+"\")")
+	captions := fs.String("captions", "", "extensions that count as captions, the first is used for saving (default \""+strings.Join(dataset.DefaultCaptionExtensions, " ")+"\")")
+	err := fs.Parse(args)
+	if err != nil {
+		if err == flag.ErrHelp {
+			return nil, 0
+		}
+		return nil, 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return nil, 2
+	}
+
+	project, problems, err := dataset.Open(fs.Arg(0), dataset.LoadOptions{
+		Recursive:         *recursive,
+		ImageExtensions:   dataset.ParseExtensions(*images),
+		CaptionExtensions: dataset.ParseExtensions(*captions),
+	})
+	for _, problem := range problems {
+		fmt.Fprintln(os.Stderr, "warning:", problem)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return project, 1
+	}
+
+	return project, -1
+}
+
+func cliconvert(args []string) int {
+	fs := newflagset("convert", "Saves the dataset in another format, the same way the gui does.")
+	to := fs.String("to", "", "output format, \"txt\", \"jsonl\" or \"metadata\" for a Hugging Face metadata.jsonl")
+	out := fs.String("o", "", "output path for jsonl and metadata (default <folder>/<folder>.jsonl and <folder>/metadata.jsonl)")
+	skipuntagged := fs.Bool("skip-untagged", false, "do not create .txt files for images without tags")
+	relative := fs.Bool("relative", false, "write jsonl image paths relative to the jsonl file")
+	pin := fs.String("pin", "", "comma separated tags that are written first, like trigger words")
+
+	project, code := parseandload(fs, args)
+	if code >= 0 {
+		return code
+	}
+	project.Pinned = dataset.ParseTags(strings.NewReader(*pin))
+
+	var err error
+	switch *to {
+	case "txt":
+		var summary dataset.TxtSummary
+		summary, err = dataset.SaveTxt(project, dataset.TxtOptions{SkipUntagged: *skipuntagged})
+		fmt.Fprintln(os.Stdout, summary)
+		if cropped := project.Cropped(); cropped > 0 {
+			fmt.Fprintf(os.Stderr, "%d crops can not be kept in .txt files\n", cropped)
+		}
+	case "jsonl":
+		path := *out
+		if path == "" {
+			path = project.JSONLPath()
+		}
+		err = dataset.SaveJSONL(project, path, dataset.JSONLOptions{RelativePaths: *relative})
+	case "metadata":
+		path := *out
+		if path == "" {
+			path = project.MetadataPath()
+		}
+		err = dataset.SaveMetadata(project, path)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown output format: %q\n", *to)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+
+	return 0
+}
+
+func cliexport(args []string) int {
+	fs := newflagset("export", "Writes a copy of the dataset with every image scaled and cropped to its target size, captions and masks go along.")
+	out := fs.String("o", "", "output folder (default <folder>_export)")
+	size := fs.Int("size", dataset.DefaultBucketOptions.Resolution, "side of the square images, or the bucket resolution with -buckets")
+	buckets := fs.Bool("buckets", false, "crop every image to its nearest aspect ratio bucket instead of a square")
+	bucketstep := fs.Int("bucket-step", dataset.DefaultBucketOptions.Step, "bucket sides are multiples of this")
+	format := fs.String("format", "png", "\"png\" or \"jpeg\"")
+	quality := fs.Int("quality", 95, "jpeg quality from 1 to 100")
+	pin := fs.String("pin", "", "comma separated tags that are written first, like trigger words")
+
+	project, code := parseandload(fs, args)
+	if code >= 0 {
+		return code
+	}
+	project.Pinned = dataset.ParseTags(strings.NewReader(*pin))
+
+	o := dataset.ExportOptions{Size: *size, Buckets: dataset.DefaultBucketOptions, Quality: *quality}
+	switch *format {
+	case "png":
+		o.Format = dataset.ExportPNG
+	case "jpeg", "jpg":
+		o.Format = dataset.ExportJPEG
+	default:
+		fmt.Fprintf(os.Stderr, "unknown image format: %q\n", *format)
+		return 2
+	}
+	if *buckets {
+		o.Size = 0
+		o.Buckets.Resolution = *size
+		o.Buckets.Step = *bucketstep
+		o.Buckets.MaxSize = max(o.Buckets.MaxSize, *size*2)
+	}
+	dir := *out
+	if dir == "" {
+		dir = filepath.Clean(project.Dir) + "_export"
+	}
+
+	summary, err := dataset.Export(project, dir, o)
+	fmt.Fprintln(os.Stdout, summary)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	return 0
+}
+
+func clivalidate(args []string) int {
+	fs := newflagset("validate", "Loads the dataset and decodes every image, exits with 1 if there were problems.")
+	requiretags := fs.Bool("tagged", false, "also treat images without tags as a problem")
+
+	project, code := parseandload(fs, args)
+	if code >= 0 {
+		return code
+	}
+
+	bad := 0
+	for _, ie := range project.Entries {
+		_, err := decodeimagefile(ie.ImagePath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			bad++
+			continue
+		}
+		if *requiretags && len(ie.Tags) < 1 {
+			fmt.Fprintf(os.Stderr, "%s: has no tags\n", ie.ImagePath)
+			bad++
+		}
+	}
+
+	fmt.Fprintf(os.Stdout, "%d images, %d with problems\n", len(project.Entries), bad)
+	if bad > 0 {
+		return 1
+	}
+	return 0
+}
+
+func clilint(args []string) int {
+	fs := newflagset("lint", "Measures every image and lists the ones that could hurt training, exits with 1 if there were any.")
+	o := dataset.DefaultLintOptions
+	fs.IntVar(&o.MinSize, "min-size", o.MinSize, "shortest allowed side in pixels")
+	fs.Float64Var(&o.MinSharpness, "min-sharpness", o.MinSharpness, "lowest allowed variance of the laplacian, lower is blurrier")
+	fs.Float64Var(&o.MaxBlockiness, "max-blockiness", o.MaxBlockiness, "highest allowed ratio of jpeg block edges to other edges, 0 turns it off")
+	minfilesize := fs.Int64("min-filesize", o.MinFileSize>>10, "smallest allowed file in KiB")
+
+	project, code := parseandload(fs, args)
+	if code >= 0 {
+		return code
+	}
+	o.MinFileSize = *minfilesize << 10
+
+	bad := 0
+	for i := range project.Entries {
+		e := &project.Entries[i]
+		r, err := dataset.LintImage(e.ImagePath, o)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			bad++
+			continue
+		}
+		if len(r.Issues) > 0 {
+			fmt.Fprintf(os.Stdout, "%s: %s (%dx%d, sharpness %.0f, blockiness %.2f, %d KiB)\n",
+				project.RelPath(e), r, r.Width, r.Height, r.Sharpness, r.Blockiness, r.FileSize>>10)
+			bad++
+		}
+	}
+
+	fmt.Fprintf(os.Stdout, "%d images, %d with issues\n", len(project.Entries), bad)
+	if bad > 0 {
+		return 1
+	}
+	return 0
+}
+
+func clistats(args []string) int {
+	fs := newflagset("stats", "Prints how many images and tags the dataset has.")
+	top := fs.Int("top", 20, "how many of the most used tags to list")
+	bucketres := fs.Int("buckets", 0, "also show the aspect ratio buckets for this base resolution, like 1024 for sdxl")
+	bucketstep := fs.Int("bucket-step", dataset.DefaultBucketOptions.Step, "bucket sides are multiples of this")
+	crop := fs.Float64("crop", 0.1, "list images that lose more than this part to cropping")
+
+	project, code := parseandload(fs, args)
+	if code >= 0 {
+		return code
+	}
+
+	printstats(os.Stdout, project, *top)
+	if *bucketres > 0 {
+		opts := dataset.DefaultBucketOptions
+		opts.Resolution = *bucketres
+		opts.Step = *bucketstep
+		opts.MaxSize = max(opts.MaxSize, *bucketres*2)
+		for i := range project.Entries {
+			err := project.Entries[i].ReadSize()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "warning:", err)
+			}
+		}
+		printbuckets(os.Stdout, project, opts, *crop)
+	}
+	return 0
+}
+
+func printbuckets(w io.Writer, p *dataset.Project, opts dataset.BucketOptions, crop float64) {
+	fits, counts := p.FitBuckets(opts)
+
+	fmt.Fprintf(w, "\nbuckets for %d with step %d:\n", opts.Resolution, opts.Step)
+	for _, c := range counts {
+		fmt.Fprintf(w, "%6d  %dx%d\n", len(c.Indexes), c.Width, c.Height)
+	}
+
+	var heavy []string
+	for i, fit := range fits {
+		if fit.Scale > 1 || fit.Crop > crop {
+			e := &p.Entries[i]
+			width, height := e.CropSize()
+			heavy = append(heavy, fmt.Sprintf("%s  %dx%d -> %dx%d, %.0f%% cropped, scaled %.2fx", p.RelPath(e), width, height, fit.Width, fit.Height, fit.Crop*100, fit.Scale))
+		}
+	}
+	if len(heavy) < 1 {
+		return
+	}
+	fmt.Fprintln(w, "\nupscaled or heavily cropped:")
+	for _, line := range heavy {
+		fmt.Fprintln(w, line)
+	}
+}
+
+func printstats(w io.Writer, p *dataset.Project, top int) {
+	counts := p.CountTags()
+	untagged := 0
+	total := 0
+	for _, ie := range p.Entries {
+		if len(ie.Tags) < 1 {
+			untagged++
+		}
+		total += len(ie.Tags)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "images:\t%d\n", len(p.Entries))
+	fmt.Fprintf(tw, "untagged:\t%d\n", untagged)
+	fmt.Fprintf(tw, "unique tags:\t%d\n", len(counts))
+	fmt.Fprintf(tw, "tags per image:\t%.1f\n", float64(total)/float64(len(p.Entries)))
+	tw.Flush()
+
+	tags := p.CollectTags()
+	if top >= 0 && top < len(tags) {
+		tags = tags[:top]
+	}
+	if len(tags) < 1 {
+		return
+	}
+
+	fmt.Fprintln(w, "\ntop tags:")
+	for _, tag := range tags {
+		fmt.Fprintf(w, "%6d  %s\n", counts[tag], tag)
+	}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// crops are outlined in this colour
+var cropcolor = color.NRGBA{R: 255, G: 200, B: 0, A: 255}
+
+// the aspect ratios the crop can be locked to, width:height
+var cropaspects = []string{"Free", "1:1", "4:3", "3:4", "3:2", "2:3", "16:9", "9:16"}
+
+// parseaspect turns width:height into width/height, 0 means free
+func parseaspect(s string) float64 {
+	w, h, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0
+	}
+	fw, err1 := strconv.ParseFloat(w, 64)
+	fh, err2 := strconv.ParseFloat(h, 64)
+	if err1 != nil || err2 != nil || fw <= 0 || fh <= 0 {
+		return 0
+	}
+	return fw / fh
+}
+
+// cropcanvas shows an image and lets a rectangle be dragged on it.
+// dragging inside the rectangle moves it, anywhere else starts a new one.
+type cropcanvas struct {
+	widget.BaseWidget
+	image *canvas.Image
+	frame *canvas.Rectangle
+
+	// size of the image in pixels
+	bounds image.Rectangle
+	// in pixels of the image, empty for no crop
+	crop image.Rectangle
+	// width/height the crop is locked to, 0 for free
+	aspect float64
+
+	// where the current drag started, in pixels
+	start  image.Point
+	moving bool
+	moved  image.Rectangle
+	drag   bool
+
+	changed bool
+	// called whenever the crop changes
+	OnChanged func()
+}
+
+var _ fyne.Draggable = (*cropcanvas)(nil)
+
+func newcropcanvas() *cropcanvas {
+	cc := &cropcanvas{
+		image: canvas.NewImageFromResource(theme.FileImageIcon()),
+		frame: &canvas.Rectangle{StrokeColor: cropcolor, StrokeWidth: 2, FillColor: color.Transparent},
+	}
+	cc.image.FillMode = canvas.ImageFillContain
+	cc.image.ScaleMode = canvas.ImageScaleSmooth
+	cc.frame.Hide()
+	cc.ExtendBaseWidget(cc)
+	return cc
+}
+
+// SetImage shows img with crop on top, crop may be nil
+func (cc *cropcanvas) SetImage(img image.Image, crop *image.Rectangle) {
+	b := img.Bounds()
+	cc.bounds = image.Rect(0, 0, b.Dx(), b.Dy())
+	cc.crop = image.Rectangle{}
+	if crop != nil {
+		cc.crop = crop.Intersect(cc.bounds)
+	}
+	cc.image.Resource = nil
+	cc.image.Image = img
+	cc.changed = false
+	cc.image.Refresh()
+	cc.update()
+}
+
+// Crop is the current crop, nil if there is none
+func (cc *cropcanvas) Crop() *image.Rectangle {
+	if cc.crop.Empty() {
+		return nil
+	}
+	crop := cc.crop
+	return &crop
+}
+
+// Clear removes the crop so the whole image is used
+func (cc *cropcanvas) Clear() {
+	cc.crop = image.Rectangle{}
+	cc.changed = true
+	cc.update()
+}
+
+// SetAspect locks the crop to width/height, the current one is shrunk to fit
+func (cc *cropcanvas) SetAspect(aspect float64) {
+	cc.aspect = aspect
+	if cc.crop.Empty() || aspect <= 0 {
+		return
+	}
+	cc.crop = cc.span(cc.crop.Min, cc.crop.Max)
+	cc.changed = true
+	cc.update()
+}
+
+// scale is how many screen units one pixel takes and offset is where the letterboxed image starts
+func (cc *cropcanvas) scale() (float32, fyne.Position) {
+	size := cc.Size()
+	w, h := float32(cc.bounds.Dx()), float32(cc.bounds.Dy())
+	scale := min(size.Width/w, size.Height/h)
+	return scale, fyne.NewPos((size.Width-w*scale)/2, (size.Height-h*scale)/2)
+}
+
+func (cc *cropcanvas) topixel(pos fyne.Position) image.Point {
+	scale, offset := cc.scale()
+	pt := image.Pt(int((pos.X-offset.X)/scale), int((pos.Y-offset.Y)/scale))
+	// dragging past the edge stops at the edge
+	pt.X = max(cc.bounds.Min.X, min(pt.X, cc.bounds.Max.X))
+	pt.Y = max(cc.bounds.Min.Y, min(pt.Y, cc.bounds.Max.Y))
+	return pt
+}
+
+// span is the rectangle from start towards end, kept to the aspect ratio
+func (cc *cropcanvas) span(start, end image.Point) image.Rectangle {
+	if cc.aspect > 0 {
+		dx, dy := end.X-start.X, end.Y-start.Y
+		w, h := float64(abs(dx)), float64(abs(dy))
+		// the side that would stick out is made shorter, so it never leaves the image
+		if w > h*cc.aspect {
+			w = h * cc.aspect
+		} else {
+			h = w / cc.aspect
+		}
+		end = image.Pt(start.X+sign(dx)*int(w), start.Y+sign(dy)*int(h))
+	}
+	return image.Rectangle{Min: start, Max: end}.Canon()
+}
+
+func (cc *cropcanvas) Dragged(ev *fyne.DragEvent) {
+	if cc.bounds.Empty() {
+		return
+	}
+	pt := cc.topixel(ev.Position)
+	if !cc.drag {
+		cc.drag = true
+		cc.start = cc.topixel(ev.Position.Subtract(ev.Dragged))
+		cc.moving = cc.start.In(cc.crop)
+		cc.moved = cc.crop
+	}
+
+	if cc.moving {
+		// moved as a whole and stopped at the edges
+		r := cc.moved.Add(pt.Sub(cc.start))
+		r = r.Sub(image.Pt(max(r.Max.X-cc.bounds.Max.X, 0), max(r.Max.Y-cc.bounds.Max.Y, 0)))
+		r = r.Add(image.Pt(max(cc.bounds.Min.X-r.Min.X, 0), max(cc.bounds.Min.Y-r.Min.Y, 0)))
+		cc.crop = r
+	} else {
+		cc.crop = cc.span(cc.start, pt)
+	}
+	cc.changed = true
+	cc.update()
+}
+
+func (cc *cropcanvas) DragEnd() {
+	cc.drag = false
+}
+
+func (cc *cropcanvas) update() {
+	if cc.crop.Empty() {
+		cc.frame.Hide()
+	} else {
+		cc.frame.Show()
+		cc.Refresh()
+	}
+	if cc.OnChanged != nil {
+		cc.OnChanged()
+	}
+}
+
+func (cc *cropcanvas) CreateRenderer() fyne.WidgetRenderer {
+	return &cropcanvasRenderer{cc: cc}
+}
+
+type cropcanvasRenderer struct {
+	cc *cropcanvas
+}
+
+func (r *cropcanvasRenderer) Destroy() {}
+
+func (r *cropcanvasRenderer) Objects() []fyne.CanvasObject {
+	return []fyne.CanvasObject{r.cc.image, r.cc.frame}
+}
+
+func (r *cropcanvasRenderer) Layout(size fyne.Size) {
+	r.cc.image.Resize(size)
+	if r.cc.bounds.Empty() || r.cc.crop.Empty() {
+		return
+	}
+	scale, offset := r.cc.scale()
+	crop := r.cc.crop
+	r.cc.frame.Move(offset.AddXY(float32(crop.Min.X)*scale, float32(crop.Min.Y)*scale))
+	r.cc.frame.Resize(fyne.NewSize(float32(crop.Dx())*scale, float32(crop.Dy())*scale))
+}
+
+func (r *cropcanvasRenderer) MinSize() fyne.Size {
+	return r.cc.image.MinSize()
+}
+
+func (r *cropcanvasRenderer) Refresh() {
+	r.Layout(r.cc.Size())
+	r.cc.frame.Refresh()
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+func sign(i int) int {
+	if i < 0 {
+		return -1
+	}
+	return 1
+}
+
+// cropimage opens a window to draw the crop of the entry at index.
+// saved gets where the entry is by then, the new crop, or nil once it was removed, and the size of the image.
+func (g *gui) cropimage(p *projectStructure, index int, saved func(index int, crop *image.Rectangle, width, height int)) {
+	e := &p.Entries[index]
+	imagepath := e.ImagePath
+	w := g.a.NewWindow("Crop - " + e.Name())
+
+	cc := newcropcanvas()
+	sizelabel := widget.NewLabel("")
+	cc.OnChanged = func() {
+		if crop := cc.Crop(); crop != nil {
+			sizelabel.SetText(fmt.Sprintf("%dx%d at %d,%d", crop.Dx(), crop.Dy(), crop.Min.X, crop.Min.Y))
+		} else {
+			sizelabel.SetText("Drag to crop")
+		}
+	}
+	cc.OnChanged()
+
+	aspect := widget.NewSelect(cropaspects, func(s string) {
+		g.a.Preferences().SetString("cropaspect", s)
+		cc.SetAspect(parseaspect(s))
+	})
+	aspect.SetSelected(g.a.Preferences().StringWithFallback("cropaspect", cropaspects[0]))
+
+	clearbutton := widget.NewButtonWithIcon("Clear", theme.ContentClearIcon(), cc.Clear)
+	savebutton := widget.NewButtonWithIcon("Save", theme.DocumentSaveIcon(), func() {
+		if cc.bounds.Empty() {
+			return
+		}
+		// the window stays open while entries are removed
+		index := p.entryindex(imagepath)
+		if index < 0 {
+			dialog.ShowInformation("Crop", "The image was removed from the project", w)
+			return
+		}
+		crop := cc.Crop()
+		p.history.SetCrop(index, crop)
+		cc.changed = false
+		saved(index, crop, cc.bounds.Dx(), cc.bounds.Dy())
+		w.Close()
+	})
+	savebutton.Importance = widget.HighImportance
+
+	w.SetCloseIntercept(func() {
+		if !cc.changed {
+			w.Close()
+			return
+		}
+		dialog.ShowConfirm("Discard Crop", "The crop was changed, close without saving it?", func(b bool) {
+			if b {
+				w.Close()
+			}
+		}, w)
+	})
+
+	toolbar := container.NewBorder(nil, nil,
+		container.NewHBox(widget.NewLabel("Aspect"), aspect),
+		container.NewHBox(clearbutton, savebutton),
+		sizelabel,
+	)
+	w.SetContent(container.NewBorder(toolbar, nil, nil, nil, cc))
+	w.Resize(fyne.NewSize(1024, 768))
+	w.Show()
+
+	crop := e.Crop
+	go func() {
+		img, err := decodeimagefile(imagepath)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		cc.SetImage(img, crop)
+	}()
+}
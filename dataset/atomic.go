@@ -0,0 +1,70 @@
+package dataset
+
+import (
+	"bufio"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// writeatomic writes to a temp file next to path and renames it over path once everything is on disk,
+// so after a crash path has either the old or the new content but never half of it
+func writeatomic(path string, write func(w io.Writer) error) (err error) {
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return &Error{Op: "write", Path: path, Err: err}
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	// CreateTemp only allows the owner, keep what the old file had
+	mode := fs.FileMode(0o644)
+	fi, err := os.Stat(path)
+	if err == nil {
+		mode = fi.Mode().Perm()
+	}
+	err = tmp.Chmod(mode)
+	if err != nil {
+		return &Error{Op: "write", Path: path, Err: err}
+	}
+
+	bw := bufio.NewWriter(tmp)
+	err = write(bw)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = tmp.Close()
+	}
+	if err != nil {
+		return &Error{Op: "write", Path: path, Err: err}
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return &Error{Op: "write", Path: path, Err: err}
+	}
+
+	syncdir(dir)
+	return nil
+}
+
+// syncdir makes the rename itself durable, not every platform can do this so errors are ignored
+func syncdir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,43 @@
+package dataset
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	os.WriteFile(path, []byte("old"), 0o640)
+
+	err := writeatomic(path, func(w io.Writer) error {
+		_, err := io.WriteString(w, "new")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := os.ReadFile(path)
+	fi, _ := os.Stat(path)
+	if string(content) != "new" || fi.Mode().Perm() != 0o640 {
+		t.Errorf("got %q with mode %v", content, fi.Mode().Perm())
+	}
+
+	// a failed write leaves the old file and no temp file behind
+	failed := errors.New("failed")
+	err = writeatomic(path, func(w io.Writer) error {
+		io.WriteString(w, "half")
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("writeatomic = %v", err)
+	}
+	content, _ = os.ReadFile(path)
+	files, _ := os.ReadDir(dir)
+	if string(content) != "new" || len(files) != 1 {
+		t.Errorf("after a failed write got %q and %d files", content, len(files))
+	}
+}
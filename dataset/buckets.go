@@ -0,0 +1,147 @@
+package dataset
+
+import (
+	"image"
+	"math"
+	"os"
+	"slices"
+)
+
+// ReadSize fills in Width and Height from the image header
+func (e *Entry) ReadSize() error {
+	width, height, err := ImageSize(e.ImagePath)
+	if err != nil {
+		return err
+	}
+	e.Width, e.Height = width, height
+	return nil
+}
+
+// ImageSize reads the size from the image header without decoding the image.
+// like image.Decode the formats have to be registered by the program.
+func ImageSize(path string) (width, height int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, &Error{Op: "read", Path: path, Err: err}
+	}
+	defer f.Close()
+
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return 0, 0, &Error{Op: "decode", Path: path, Err: err}
+	}
+	return cfg.Width, cfg.Height, nil
+}
+
+// BucketOptions are the settings of aspect ratio bucketing like kohya sd-scripts does it
+type BucketOptions struct {
+	// buckets have at most Resolution*Resolution pixels, 1024 for sdxl and 512 for sd1.5
+	Resolution int
+	// sides are multiples of this
+	Step int
+	// no side is shorter or longer than these
+	MinSize, MaxSize int
+}
+
+// DefaultBucketOptions are the sd-scripts defaults for sdxl
+var DefaultBucketOptions = BucketOptions{Resolution: 1024, Step: 64, MinSize: 256, MaxSize: 2048}
+
+// Bucket is a training resolution
+type Bucket struct {
+	Width, Height int
+}
+
+// BucketFit is how an image ends up in its bucket
+type BucketFit struct {
+	Bucket
+	// how much the image is resized before cropping, above 1 means upscaling
+	Scale float64
+	// the part of the resized image that is cropped away, between 0 and 1
+	Crop float64
+}
+
+// Buckets lists all resolutions the options allow, without duplicates
+func (o BucketOptions) Buckets() []Bucket {
+	step := max(o.Step, 1)
+	maxarea := o.Resolution * o.Resolution
+	var buckets []Bucket
+	add := func(b Bucket) {
+		if !slices.Contains(buckets, b) {
+			buckets = append(buckets, b)
+		}
+	}
+	// the same walk sd-scripts does in make_buckets
+	for width := o.MinSize; width <= o.MaxSize; width += step {
+		height := min(o.MaxSize, (maxarea/width)/step*step)
+		if height < o.MinSize {
+			continue
+		}
+		add(Bucket{width, height})
+		add(Bucket{height, width})
+	}
+	// and the square one
+	side := min(o.MaxSize, o.Resolution/step*step)
+	add(Bucket{side, side})
+
+	slices.SortFunc(buckets, func(a, b Bucket) int {
+		return a.Width*b.Height - b.Width*a.Height
+	})
+	return buckets
+}
+
+// FitBucket puts an image of the size into the bucket with the closest aspect ratio,
+// it is scaled to cover the bucket and the rest is cropped
+func FitBucket(buckets []Bucket, width, height int) BucketFit {
+	if len(buckets) < 1 || width < 1 || height < 1 {
+		return BucketFit{}
+	}
+	aspect := float64(width) / float64(height)
+	best := buckets[0]
+	for _, b := range buckets[1:] {
+		if math.Abs(float64(b.Width)/float64(b.Height)-aspect) < math.Abs(float64(best.Width)/float64(best.Height)-aspect) {
+			best = b
+		}
+	}
+
+	scale := max(float64(best.Width)/float64(width), float64(best.Height)/float64(height))
+	resized := float64(width) * scale * float64(height) * scale
+	return BucketFit{
+		Bucket: best,
+		Scale:  scale,
+		Crop:   1 - float64(best.Width*best.Height)/resized,
+	}
+}
+
+// BucketCount is how many images go into one bucket
+type BucketCount struct {
+	Bucket
+	Indexes []int
+}
+
+// FitBuckets puts every entry with a known size into its bucket, cropped ones by the size of their crop.
+// fits has one entry per project entry, the ones without a size are left empty.
+// counts has the used buckets, the fullest first.
+func (p *Project) FitBuckets(o BucketOptions) (fits []BucketFit, counts []BucketCount) {
+	buckets := o.Buckets()
+	fits = make([]BucketFit, len(p.Entries))
+	bybucket := make(map[Bucket][]int)
+	for i, e := range p.Entries {
+		width, height := e.CropSize()
+		if width < 1 || height < 1 {
+			continue
+		}
+		fits[i] = FitBucket(buckets, width, height)
+		bybucket[fits[i].Bucket] = append(bybucket[fits[i].Bucket], i)
+	}
+
+	for b, indexes := range bybucket {
+		counts = append(counts, BucketCount{Bucket: b, Indexes: indexes})
+	}
+	slices.SortFunc(counts, func(a, b BucketCount) int {
+		if len(a.Indexes) != len(b.Indexes) {
+			return len(b.Indexes) - len(a.Indexes)
+		}
+		return a.Width*b.Height - b.Width*a.Height
+	})
+	return fits, counts
+}
@@ -0,0 +1,96 @@
+package dataset
+
+import (
+	"image"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestBuckets(t *testing.T) {
+	o := DefaultBucketOptions
+	buckets := o.Buckets()
+	if !slices.Contains(buckets, Bucket{1024, 1024}) {
+		t.Error("the square bucket is missing")
+	}
+	for i, b := range buckets {
+		if b.Width%o.Step != 0 || b.Height%o.Step != 0 {
+			t.Errorf("%v is not a multiple of the step", b)
+		}
+		if b.Width*b.Height > o.Resolution*o.Resolution {
+			t.Errorf("%v has more pixels than the resolution", b)
+		}
+		if min(b.Width, b.Height) < o.MinSize || max(b.Width, b.Height) > o.MaxSize {
+			t.Errorf("%v is outside of the min and max size", b)
+		}
+		if i > 0 && buckets[i-1].Width*b.Height >= b.Width*buckets[i-1].Height {
+			t.Errorf("%v and %v are not sorted by aspect ratio or are the same", buckets[i-1], b)
+		}
+	}
+}
+
+func TestFitBucket(t *testing.T) {
+	buckets := DefaultBucketOptions.Buckets()
+	tests := []struct {
+		width, height int
+		want          Bucket
+		scale         float64
+	}{
+		{2048, 2048, Bucket{1024, 1024}, 0.5},
+		{512, 512, Bucket{1024, 1024}, 2},
+		{1920, 1080, Bucket{1344, 768}, 0.7111},
+		{1080, 1920, Bucket{768, 1344}, 0.7111},
+	}
+	for _, tt := range tests {
+		fit := FitBucket(buckets, tt.width, tt.height)
+		if fit.Bucket != tt.want || math.Abs(fit.Scale-tt.scale) > 0.001 {
+			t.Errorf("FitBucket(%dx%d) = %+v, want %v scaled %v", tt.width, tt.height, fit, tt.want, tt.scale)
+		}
+		if fit.Crop < 0 || fit.Crop > 0.1 {
+			t.Errorf("FitBucket(%dx%d) crops %v", tt.width, tt.height, fit.Crop)
+		}
+	}
+	if fit := FitBucket(buckets, 0, 10); fit != (BucketFit{}) {
+		t.Errorf("an image without a size got %+v", fit)
+	}
+}
+
+func TestFitBuckets(t *testing.T) {
+	crop := image.Rect(0, 0, 1000, 1000)
+	p := &Project{Entries: []Entry{
+		{Width: 2048, Height: 2048},
+		{Width: 1000, Height: 2000, Crop: &crop},
+		{},
+		{Width: 1920, Height: 1080},
+	}}
+	fits, counts := p.FitBuckets(DefaultBucketOptions)
+	if fits[1].Bucket != (Bucket{1024, 1024}) {
+		t.Errorf("the crop was not used, got %v", fits[1].Bucket)
+	}
+	if fits[2] != (BucketFit{}) {
+		t.Errorf("an entry without a size got %+v", fits[2])
+	}
+	want := []BucketCount{{Bucket{1024, 1024}, []int{0, 1}}, {Bucket{1344, 768}, []int{3}}}
+	if !slices.EqualFunc(counts, want, func(a, b BucketCount) bool {
+		return a.Bucket == b.Bucket && slices.Equal(a.Indexes, b.Indexes)
+	}) {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+}
+
+func TestCroprect(t *testing.T) {
+	tests := []struct {
+		b    image.Rectangle
+		w, h int
+		want image.Rectangle
+	}{
+		{image.Rect(0, 0, 100, 50), 1, 1, image.Rect(25, 0, 75, 50)},
+		{image.Rect(0, 0, 50, 100), 1, 1, image.Rect(0, 25, 50, 75)},
+		{image.Rect(10, 10, 110, 60), 2, 1, image.Rect(10, 10, 110, 60)},
+	}
+	for _, tt := range tests {
+		if got := croprect(tt.b, tt.w, tt.h); got != tt.want {
+			t.Errorf("croprect(%v, %d, %d) = %v, want %v", tt.b, tt.w, tt.h, got, tt.want)
+		}
+	}
+}
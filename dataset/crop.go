@@ -0,0 +1,63 @@
+package dataset
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+)
+
+// the jsonl column crops are kept in, as [left, top, right, bottom] in pixels of the original image
+const cropcolumn = "crop"
+
+// cropvalue is what gets written to the crop column
+func cropvalue(crop *image.Rectangle) [4]int {
+	return [4]int{crop.Min.X, crop.Min.Y, crop.Max.X, crop.Max.Y}
+}
+
+// parsecrop reads the crop column, an empty one is no crop
+func parsecrop(raw json.RawMessage) (*image.Rectangle, error) {
+	var v *[4]int
+	err := json.Unmarshal(raw, &v)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	crop := image.Rect(v[0], v[1], v[2], v[3])
+	if crop.Empty() {
+		return nil, fmt.Errorf("crop %v is empty", *v)
+	}
+	return &crop, nil
+}
+
+// takecrop removes the crop column from columns if it holds a crop.
+// anything else in it was written by another tool and stays, a crop drawn later replaces it on save.
+func takecrop(columns map[string]json.RawMessage) *image.Rectangle {
+	raw, ok := columns[cropcolumn]
+	if !ok {
+		return nil
+	}
+	crop, err := parsecrop(raw)
+	if err != nil || crop == nil {
+		return nil
+	}
+	delete(columns, cropcolumn)
+	return crop
+}
+
+// CropSize is the size of the image after its crop, zero until the size is known
+func (e *Entry) CropSize() (width, height int) {
+	if e.Crop != nil {
+		return e.Crop.Dx(), e.Crop.Dy()
+	}
+	return e.Width, e.Height
+}
+
+// Cropped counts the entries that have a crop
+func (p *Project) Cropped() int {
+	n := 0
+	for _, e := range p.Entries {
+		if e.Crop != nil {
+			n++
+		}
+	}
+	return n
+}
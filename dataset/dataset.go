@@ -0,0 +1,287 @@
+// Package dataset loads, edits and saves tagged image datasets.
+// It does not depend on the gui so other tools can use the same rules.
+package dataset
+
+import (
+	"encoding/json"
+	"errors"
+	"image"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Entry is a single image and its tags
+type Entry struct {
+	// absolute path to the image
+	ImagePath string
+	Tags      []string
+	// for jsonl to jsonl only
+	Mask *string
+	// fields of a jsonl or metadata.jsonl that are not handled here, they are written back as they were
+	Extra map[string]json.RawMessage
+	// size of the original image, zero until ReadSize
+	Width, Height int
+	// the part of the image that is used for training, nil for all of it
+	Crop *image.Rectangle
+}
+
+// Project is a loaded dataset
+type Project struct {
+	// the folder or jsonl file the dataset was loaded from
+	Source string
+	// the folder output goes to
+	Dir     string
+	Entries []Entry
+	// tags that are always written before all others, in this order
+	Pinned []string
+	// what caption files are saved as, empty means .txt
+	CaptionExtension string
+}
+
+var (
+	ErrUnknownExtension = errors.New("unhandled file extension")
+	ErrNoImage          = errors.New("has no assosiacted image")
+	ErrDuplicateImage   = errors.New("another image has the same name")
+	ErrDuplicateCaption = errors.New("another caption file for the same image was used")
+	ErrNothingUseable   = errors.New("there was nothing useable")
+	ErrInvalidTag       = errors.New("invalid tag")
+)
+
+// Error records which file an error happened with
+type Error struct {
+	Op   string
+	Path string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Op + " " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// TagError records which tag an error happened with
+type TagError struct {
+	Tag string
+	Err error
+}
+
+func (e *TagError) Error() string {
+	return e.Err.Error() + ": \"" + e.Tag + "\""
+}
+
+func (e *TagError) Unwrap() error {
+	return e.Err
+}
+
+// Name returns the file name of the image
+func (e *Entry) Name() string {
+	return filepath.Base(e.ImagePath)
+}
+
+// RelPath is the image path relative to the project folder, so images in subfolders can be told apart.
+// images outside of it keep their absolute path.
+func (p *Project) RelPath(e *Entry) string {
+	rel, ok := inside(p.Dir, e.ImagePath)
+	if !ok {
+		return e.ImagePath
+	}
+	return rel
+}
+
+// Copy returns a project that edits of p do not reach,
+// so long work like exporting does not have to hold the history lock
+func (p *Project) Copy() *Project {
+	c := *p
+	c.Pinned = slices.Clone(p.Pinned)
+	c.Entries = slices.Clone(p.Entries)
+	for i := range c.Entries {
+		e := &c.Entries[i]
+		e.Tags = slices.Clone(e.Tags)
+		e.Extra = maps.Clone(e.Extra)
+		if e.Mask != nil {
+			mask := *e.Mask
+			e.Mask = &mask
+		}
+		if e.Crop != nil {
+			crop := *e.Crop
+			e.Crop = &crop
+		}
+	}
+	return &c
+}
+
+// inside returns path relative to dir if it is somewhere below dir
+func inside(dir, path string) (string, bool) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// NormaliseTag trims the tag and makes sure it can be stored in a caption
+func NormaliseTag(tag string) (string, error) {
+	trimmed := strings.TrimSpace(tag)
+	if trimmed == "" || strings.ContainsAny(trimmed, ",\n") {
+		return "", &TagError{Tag: tag, Err: ErrInvalidTag}
+	}
+	return trimmed, nil
+}
+
+// HasTag reports if the entry has the tag
+func (e *Entry) HasTag(tag string) bool {
+	return slices.Contains(e.Tags, tag)
+}
+
+// AddTag appends the tag if the entry does not have it yet
+func (e *Entry) AddTag(tag string) error {
+	tag, err := NormaliseTag(tag)
+	if err != nil {
+		return err
+	}
+	if !e.HasTag(tag) {
+		e.Tags = append(e.Tags, tag)
+	}
+	return nil
+}
+
+// RemoveTag removes the tag and reports if it was there
+func (e *Entry) RemoveTag(tag string) bool {
+	i := slices.Index(e.Tags, tag)
+	if i < 0 {
+		return false
+	}
+	e.Tags = slices.Delete(e.Tags, i, i+1)
+	return true
+}
+
+// SetTags replaces all tags, duplicates are dropped
+func (e *Entry) SetTags(tags []string) error {
+	final := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag, err := NormaliseTag(tag)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(final, tag) {
+			final = append(final, tag)
+		}
+	}
+	e.Tags = final
+	return nil
+}
+
+// ReplaceTags swaps every tag in from for to, which takes the place of the first one found.
+// this is both renaming and merging.
+func (e *Entry) ReplaceTags(from []string, to string) error {
+	to, err := NormaliseTag(to)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(e.Tags, func(tag string) bool { return slices.Contains(from, tag) }) {
+		return nil
+	}
+
+	replaced := make([]string, 0, len(e.Tags))
+	placed := false
+	for _, tag := range e.Tags {
+		if tag == to || slices.Contains(from, tag) {
+			if !placed {
+				replaced = append(replaced, to)
+				placed = true
+			}
+			continue
+		}
+		replaced = append(replaced, tag)
+	}
+	e.Tags = replaced
+	return nil
+}
+
+// RemoveTags removes all of the tags
+func (e *Entry) RemoveTags(tags []string) {
+	e.Tags = slices.DeleteFunc(e.Tags, func(tag string) bool { return slices.Contains(tags, tag) })
+}
+
+// OrderedTags puts the pinned tags the entry has in front, the rest keep their order
+func (p *Project) OrderedTags(e *Entry) []string {
+	ordered := make([]string, 0, len(e.Tags))
+	for _, tag := range p.Pinned {
+		if e.HasTag(tag) && !slices.Contains(ordered, tag) {
+			ordered = append(ordered, tag)
+		}
+	}
+	for _, tag := range e.Tags {
+		if !slices.Contains(p.Pinned, tag) {
+			ordered = append(ordered, tag)
+		}
+	}
+	return ordered
+}
+
+// WithAnyTag returns the indexes of the entries that have at least one of the tags
+func (p *Project) WithAnyTag(tags []string) []int {
+	var indexes []int
+	for i, e := range p.Entries {
+		if slices.ContainsFunc(e.Tags, func(tag string) bool { return slices.Contains(tags, tag) }) {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// AddTagToAll adds the tag to every entry
+func (p *Project) AddTagToAll(tag string) error {
+	tag, err := NormaliseTag(tag)
+	if err != nil {
+		return err
+	}
+	for i := range p.Entries {
+		p.Entries[i].AddTag(tag)
+	}
+	return nil
+}
+
+// CountTags returns how many entries use each tag
+func (p *Project) CountTags() map[string]int {
+	collect := make(map[string]int)
+	for _, e := range p.Entries {
+		for _, tag := range e.Tags {
+			collect[tag]++
+		}
+	}
+	return collect
+}
+
+// CollectTags returns all tags, the most used first
+func (p *Project) CollectTags() (tags []string) {
+	// count them
+	collect := p.CountTags()
+	// sort them
+	type tag_tmp struct {
+		tag   string
+		count int
+	}
+	var tagged []tag_tmp
+	for ttag, count := range collect {
+		tagged = append(tagged, tag_tmp{tag: ttag, count: count})
+	}
+	slices.SortFunc(tagged, func(a, b tag_tmp) int {
+		res := b.count - a.count
+		if res == 0 {
+			// makes the list stable
+			res = strings.Compare(a.tag, b.tag)
+		}
+		return res
+	})
+	// copy them over
+	for _, data := range tagged {
+		tags = append(tags, data.tag)
+	}
+	return tags
+}
@@ -0,0 +1,137 @@
+package dataset
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writepng puts a w by h image at path, filled with c
+func writepng(t *testing.T, path string, w, h int, c color.Color) {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, c)
+		}
+	}
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	err = png.Encode(f, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		caption string
+		want    []string
+	}{
+		{"", []string{}},
+		{"a, b, c", []string{"a", "b", "c"}},
+		{" a ,b,,  c ,", []string{"a", "b", "c"}},
+		{"a, b, a", []string{"a", "b"}},
+		{"a, b\nc, d", []string{"a", "b", "c", "d"}},
+		{"long hair, pokemon (creature)", []string{"long hair", "pokemon (creature)"}},
+	}
+	for _, tt := range tests {
+		got := ParseTags(strings.NewReader(tt.caption))
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("ParseTags(%q) = %q, want %q", tt.caption, got, tt.want)
+		}
+	}
+}
+
+func TestNormaliseTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+		ok   bool
+	}{
+		{"a", "a", true},
+		{"  long hair ", "long hair", true},
+		{"", "", false},
+		{"   ", "", false},
+		{"a, b", "", false},
+		{"a\nb", "", false},
+	}
+	for _, tt := range tests {
+		got, err := NormaliseTag(tt.tag)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("NormaliseTag(%q) = %q, %v", tt.tag, got, err)
+		}
+	}
+}
+
+func TestEntryTags(t *testing.T) {
+	e := Entry{}
+	for _, tag := range []string{"a", " b", "a", "c "} {
+		err := e.AddTag(tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !slices.Equal(e.Tags, []string{"a", "b", "c"}) {
+		t.Fatalf("AddTag gave %q", e.Tags)
+	}
+	if e.AddTag("x,y") == nil {
+		t.Error("AddTag took a tag with a comma")
+	}
+
+	if !e.RemoveTag("b") || e.RemoveTag("b") {
+		t.Error("RemoveTag should only report the first removal")
+	}
+
+	// renaming and merging keep the place of the first tag found
+	e.Tags = []string{"x", "blonde", "y", "blond hair", "z"}
+	err := e.ReplaceTags([]string{"blonde", "blond hair"}, "blonde hair")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"x", "blonde hair", "y", "z"}; !slices.Equal(e.Tags, want) {
+		t.Errorf("ReplaceTags gave %q, want %q", e.Tags, want)
+	}
+}
+
+func TestOrderedTags(t *testing.T) {
+	p := &Project{Pinned: []string{"trigger", "1girl", "missing"}}
+	e := &Entry{Tags: []string{"smile", "1girl", "trigger", "solo"}}
+	if want := []string{"trigger", "1girl", "smile", "solo"}; !slices.Equal(p.OrderedTags(e), want) {
+		t.Errorf("OrderedTags = %q, want %q", p.OrderedTags(e), want)
+	}
+	if want := "trigger, 1girl, smile, solo"; p.Caption(e) != want {
+		t.Errorf("Caption = %q, want %q", p.Caption(e), want)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	mask := "/a_mask.png"
+	crop := image.Rect(1, 2, 3, 4)
+	p := &Project{
+		Pinned:  []string{"a"},
+		Entries: []Entry{{ImagePath: "/a.png", Tags: []string{"a", "b"}, Mask: &mask, Crop: &crop}},
+	}
+	c := p.Copy()
+	p.Pinned[0] = "changed"
+	p.Entries[0].Tags[0] = "changed"
+	*p.Entries[0].Mask = "changed"
+	p.Entries[0].Crop.Min.X = 0
+
+	e := c.Entries[0]
+	if c.Pinned[0] != "a" || e.Tags[0] != "a" || *e.Mask != "/a_mask.png" || e.Crop.Min.X != 1 {
+		t.Errorf("the copy was changed with the original: %+v", c)
+	}
+}
@@ -0,0 +1,231 @@
+package dataset
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+var ErrOverwriteOriginal = errors.New("would overwrite the original")
+
+// ExportFormat is what the exported images are encoded as
+type ExportFormat int
+
+const (
+	ExportPNG ExportFormat = iota
+	ExportJPEG
+)
+
+func (f ExportFormat) String() string {
+	if f == ExportJPEG {
+		return "jpeg"
+	}
+	return "png"
+}
+
+// Ext is the file extension for the format
+func (f ExportFormat) Ext() string {
+	if f == ExportJPEG {
+		return ".jpg"
+	}
+	return ".png"
+}
+
+// ExportOptions are what Export does to every image
+type ExportOptions struct {
+	// every image becomes a square this big, 0 puts them into buckets instead
+	Size    int
+	Buckets BucketOptions
+	Format  ExportFormat
+	// 1 to 100, only for jpeg
+	Quality int
+	// called after every entry
+	Progress func(done int)
+}
+
+// ExportSummary counts what Export wrote
+type ExportSummary struct {
+	Images, Captions, Masks int
+}
+
+func (s ExportSummary) String() string {
+	return fmt.Sprintf("%d images, %d captions and %d masks exported", s.Images, s.Captions, s.Masks)
+}
+
+// Export writes a copy of the dataset into dir that is ready for training.
+// images are cut to their crop if they have one, scaled to cover their target size
+// and the rest is cropped from the middle.
+// captions and masks are written next to them under the same name like Load expects them.
+// subfolders of the dataset are kept, images outside of it end up in dir itself.
+// images that would end up with the same name are not exported and reported instead.
+func Export(p *Project, dir string, o ExportOptions) (ExportSummary, error) {
+	var summary ExportSummary
+	if samefile(dir, p.Dir) {
+		return summary, &Error{Op: "export", Path: dir, Err: ErrOverwriteOriginal}
+	}
+	var buckets []Bucket
+	if o.Size < 1 {
+		buckets = o.Buckets.Buckets()
+	}
+
+	// images with the same name but another extension or from another folder would overwrite each other
+	stems := make([]string, len(p.Entries))
+	used := make(map[string]int, len(p.Entries))
+	for i := range p.Entries {
+		stems[i] = exportstem(p, &p.Entries[i], dir)
+		used[stems[i]]++
+	}
+
+	var errs []error
+	for i := range p.Entries {
+		e := &p.Entries[i]
+		var err error
+		if used[stems[i]] > 1 {
+			err = &Error{Op: "export", Path: e.ImagePath, Err: ErrDuplicateImage}
+		} else {
+			err = exportentry(p, e, stems[i], o, buckets, &summary)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if o.Progress != nil {
+			o.Progress(i + 1)
+		}
+	}
+	return summary, errors.Join(errs...)
+}
+
+// exportstem is where the files of e go in dir, without an extension
+func exportstem(p *Project, e *Entry, dir string) string {
+	rel, ok := inside(p.Dir, e.ImagePath)
+	if !ok {
+		rel = filepath.Base(e.ImagePath)
+	}
+	return filepath.Join(dir, strings.TrimSuffix(rel, filepath.Ext(rel)))
+}
+
+func exportentry(p *Project, e *Entry, stem string, o ExportOptions, buckets []Bucket, summary *ExportSummary) error {
+	out := stem + o.Format.Ext()
+	if samefile(out, e.ImagePath) {
+		return &Error{Op: "export", Path: out, Err: ErrOverwriteOriginal}
+	}
+
+	img, err := decodefile(e.ImagePath)
+	if err != nil {
+		return err
+	}
+	b := img.Bounds()
+	// the crop of the entry decides what is left before it is fitted to the size
+	area := b
+	if e.Crop != nil && !e.Crop.Add(b.Min).Intersect(b).Empty() {
+		area = e.Crop.Add(b.Min).Intersect(b)
+	}
+	size := Bucket{o.Size, o.Size}
+	if o.Size < 1 {
+		size = FitBucket(buckets, area.Dx(), area.Dy()).Bucket
+	}
+	src := croprect(area, size.Width, size.Height)
+
+	err = os.MkdirAll(filepath.Dir(out), 0o755)
+	if err != nil {
+		return &Error{Op: "export", Path: out, Err: err}
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, size.Width, size.Height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, src, draw.Src, nil)
+	err = writeatomic(out, func(w io.Writer) error {
+		if o.Format == ExportJPEG {
+			return jpeg.Encode(w, dst, &jpeg.Options{Quality: o.Quality})
+		}
+		return png.Encode(w, dst)
+	})
+	if err != nil {
+		return err
+	}
+	summary.Images++
+
+	ext := p.CaptionExtension
+	if ext == "" {
+		ext = ".txt"
+	}
+	err = writeatomic(stem+ext, func(w io.Writer) error {
+		_, err := io.WriteString(w, p.Caption(e))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	summary.Captions++
+
+	if e.Mask == nil {
+		return nil
+	}
+	mask, err := decodefile(*e.Mask)
+	if err != nil {
+		return err
+	}
+	// masks are not always saved at the size of their image, so the crop is taken relative
+	mb := mask.Bounds()
+	scalex := float64(mb.Dx()) / float64(b.Dx())
+	scaley := float64(mb.Dy()) / float64(b.Dy())
+	masksrc := image.Rect(
+		mb.Min.X+int(float64(src.Min.X-b.Min.X)*scalex), mb.Min.Y+int(float64(src.Min.Y-b.Min.Y)*scaley),
+		mb.Min.X+int(float64(src.Max.X-b.Min.X)*scalex), mb.Min.Y+int(float64(src.Max.Y-b.Min.Y)*scaley),
+	)
+	maskdst := image.NewGray(dst.Bounds())
+	draw.BiLinear.Scale(maskdst, maskdst.Bounds(), mask, masksrc, draw.Src, nil)
+	err = SaveMask(stem+MaskSuffix+".png", maskdst)
+	if err != nil {
+		return err
+	}
+	summary.Masks++
+	return nil
+}
+
+// croprect is the middle part of b with the aspect ratio of w by h
+func croprect(b image.Rectangle, w, h int) image.Rectangle {
+	bw, bh := b.Dx(), b.Dy()
+	if bw*h > bh*w {
+		cw := bh * w / h
+		x := b.Min.X + (bw-cw)/2
+		return image.Rect(x, b.Min.Y, x+cw, b.Max.Y)
+	}
+	ch := bw * h / w
+	y := b.Min.Y + (bh-ch)/2
+	return image.Rect(b.Min.X, y, b.Max.X, y+ch)
+}
+
+func samefile(a, b string) bool {
+	fa, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	fb, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(fa, fb)
+}
+
+// decodefile is image.Decode with the errors of this package
+func decodefile(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, &Error{Op: "read", Path: path, Err: err}
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, &Error{Op: "decode", Path: path, Err: err}
+	}
+	return img, nil
+}
@@ -0,0 +1,111 @@
+package dataset
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+	// only the middle of a survives a square crop
+	a := image.NewNRGBA(image.Rect(0, 0, 40, 20))
+	for y := range 20 {
+		for x := range 40 {
+			c := color.NRGBA{255, 255, 255, 255}
+			if x < 10 || x >= 30 {
+				c = color.NRGBA{255, 0, 0, 255}
+			}
+			a.SetNRGBA(x, y, c)
+		}
+	}
+	saveimage(t, filepath.Join(dir, "a.png"), a, 0)
+	writepng(t, filepath.Join(dir, "sub", "b.png"), 30, 30, color.Black)
+	// a mask at half the size of its image
+	writepng(t, filepath.Join(dir, "sub", "b_mask.png"), 15, 15, color.White)
+
+	p, _, err := LoadDir(dir, LoadOptions{Recursive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range p.Entries {
+		p.Entries[i].AddTag("x")
+	}
+
+	out := filepath.Join(t.TempDir(), "out")
+	summary, err := Export(p, out, ExportOptions{Size: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary != (ExportSummary{Images: 2, Captions: 2, Masks: 1}) {
+		t.Errorf("Export = %+v", summary)
+	}
+	for _, name := range []string{"a.png", filepath.Join("sub", "b.png"), filepath.Join("sub", "b_mask.png")} {
+		img, err := decodefile(filepath.Join(out, name))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if img.Bounds() != image.Rect(0, 0, 16, 16) {
+			t.Errorf("%s is %v", name, img.Bounds())
+		}
+		if r, g, _, _ := img.At(0, 8).RGBA(); name == "a.png" && (r>>8 < 250 || g>>8 < 250) {
+			t.Errorf("a.png was not cropped from the middle")
+		}
+	}
+	caption, _ := os.ReadFile(filepath.Join(out, "sub", "b.txt"))
+	if string(caption) != "x" {
+		t.Errorf("sub/b.txt = %q", caption)
+	}
+
+	// buckets keep the aspect ratio close
+	summary, err = Export(p, out, ExportOptions{Buckets: DefaultBucketOptions, Format: ExportJPEG, Quality: 90})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := decodefile(filepath.Join(out, "a.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := FitBucket(DefaultBucketOptions.Buckets(), 40, 20).Bucket
+	if img.Bounds() != image.Rect(0, 0, want.Width, want.Height) {
+		t.Errorf("a.jpg is %v, want %v", img.Bounds(), want)
+	}
+
+	_, err = Export(p, dir, ExportOptions{Size: 16})
+	if !errors.Is(err, ErrOverwriteOriginal) {
+		t.Errorf("exporting into the dataset = %v", err)
+	}
+}
+
+func TestExportSameName(t *testing.T) {
+	dir := t.TempDir()
+	other := t.TempDir()
+	writepng(t, filepath.Join(dir, "a.png"), 4, 4, color.White)
+	saveimage(t, filepath.Join(dir, "a.jpg"), image.NewGray(image.Rect(0, 0, 4, 4)), 90)
+	writepng(t, filepath.Join(dir, "b.png"), 4, 4, color.White)
+	writepng(t, filepath.Join(other, "b.png"), 4, 4, color.Black)
+	writepng(t, filepath.Join(dir, "c.png"), 4, 4, color.White)
+
+	p := &Project{Dir: dir}
+	for _, path := range []string{"a.png", "a.jpg", "b.png", "c.png"} {
+		p.Entries = append(p.Entries, Entry{ImagePath: filepath.Join(dir, path)})
+	}
+	p.Entries = append(p.Entries, Entry{ImagePath: filepath.Join(other, "b.png")})
+
+	out := t.TempDir()
+	summary, err := Export(p, out, ExportOptions{Size: 4})
+	if !errors.Is(err, ErrDuplicateImage) {
+		t.Errorf("Export = %v", err)
+	}
+	if summary.Images != 1 {
+		t.Errorf("exported %d images, want only c.png", summary.Images)
+	}
+	files, _ := os.ReadDir(out)
+	if len(files) != 2 {
+		t.Errorf("got %d files, want c.png and c.txt", len(files))
+	}
+}
@@ -0,0 +1,270 @@
+package dataset
+
+import (
+	"image"
+	"slices"
+	"sync"
+)
+
+// how many steps can be undone
+const maxhistory = 500
+
+// Edit is a tag change on one or more entries that is undone and redone as one step
+type Edit struct {
+	// what happened, shown to the user
+	Name    string
+	changes []tagchange
+}
+
+type tagchange struct {
+	index  int
+	before []string
+	after  []string
+}
+
+// History records the edits made to a project.
+// all changes to the tags should go through it so it knows when they are unsaved.
+type History struct {
+	p    *Project
+	undo []*Edit
+	redo []*Edit
+	// what was on top of undo when it was last saved
+	saved   *Edit
+	version uint64
+	// edits lock this so the project can be read from other goroutines with Read
+	mu sync.Mutex
+
+	// called after every change to the history
+	OnChanged func()
+}
+
+func NewHistory(p *Project) *History {
+	return &History{p: p}
+}
+
+// Do runs change on the entries at indexes and records it as a single step.
+// if change fails on any entry, nothing is changed.
+// it reports if anything was actually changed.
+func (h *History) Do(name string, indexes []int, change func(e *Entry) error) (bool, error) {
+	h.mu.Lock()
+	changed, err := h.do(name, indexes, change)
+	h.mu.Unlock()
+	if changed {
+		h.changed()
+	}
+	return changed, err
+}
+
+func (h *History) do(name string, indexes []int, change func(e *Entry) error) (bool, error) {
+	edit := &Edit{Name: name}
+	for _, i := range indexes {
+		e := &h.p.Entries[i]
+		before := slices.Clone(e.Tags)
+
+		err := change(e)
+		if err != nil {
+			e.Tags = before
+			edit.revert(h.p)
+			return false, err
+		}
+
+		if !slices.Equal(before, e.Tags) {
+			edit.changes = append(edit.changes, tagchange{index: i, before: before, after: slices.Clone(e.Tags)})
+		}
+	}
+
+	if len(edit.changes) < 1 {
+		return false, nil
+	}
+
+	h.undo = append(h.undo, edit)
+	if len(h.undo) > maxhistory {
+		h.undo = slices.Delete(h.undo, 0, len(h.undo)-maxhistory)
+	}
+	h.redo = nil
+	h.version++
+	return true, nil
+}
+
+// DoAll is Do for every entry in the project
+func (h *History) DoAll(name string, change func(e *Entry) error) (bool, error) {
+	indexes := make([]int, len(h.p.Entries))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	return h.Do(name, indexes, change)
+}
+
+// Undo reverts the last edit and returns it, nil if there was nothing to undo
+func (h *History) Undo() *Edit {
+	h.mu.Lock()
+	if len(h.undo) < 1 {
+		h.mu.Unlock()
+		return nil
+	}
+	edit := h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+
+	edit.revert(h.p)
+	h.redo = append(h.redo, edit)
+	h.version++
+	h.mu.Unlock()
+
+	h.changed()
+	return edit
+}
+
+// Redo applies the last undone edit again and returns it, nil if there was nothing to redo
+func (h *History) Redo() *Edit {
+	h.mu.Lock()
+	if len(h.redo) < 1 {
+		h.mu.Unlock()
+		return nil
+	}
+	edit := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+
+	edit.apply(h.p)
+	h.undo = append(h.undo, edit)
+	h.version++
+	h.mu.Unlock()
+
+	h.changed()
+	return edit
+}
+
+// NextUndo is the edit Undo would revert, or nil
+func (h *History) NextUndo() *Edit {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.nextundo()
+}
+
+func (h *History) nextundo() *Edit {
+	if len(h.undo) < 1 {
+		return nil
+	}
+	return h.undo[len(h.undo)-1]
+}
+
+// NextRedo is the edit Redo would apply, or nil
+func (h *History) NextRedo() *Edit {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.redo) < 1 {
+		return nil
+	}
+	return h.redo[len(h.redo)-1]
+}
+
+// Remove takes the entries at indexes out of the project.
+// the edits refer to entries by index, so the history is cleared.
+func (h *History) Remove(indexes []int) {
+	h.mu.Lock()
+	kept := make([]Entry, 0, len(h.p.Entries))
+	for i, e := range h.p.Entries {
+		if !slices.Contains(indexes, i) {
+			kept = append(kept, e)
+		}
+	}
+	h.p.Entries = kept
+	h.mu.Unlock()
+
+	h.Clear()
+}
+
+// Clear forgets everything, for when entries get added or removed
+func (h *History) Clear() {
+	h.mu.Lock()
+	h.undo = nil
+	h.redo = nil
+	// there is nothing to undo back to the saved state anymore
+	h.saved = &Edit{}
+	h.version++
+	h.mu.Unlock()
+
+	h.changed()
+}
+
+// SetMask changes the mask of the entry at index.
+// it can not be undone, so the project stays unsaved until the next save.
+func (h *History) SetMask(index int, mask string) {
+	h.mu.Lock()
+	h.p.Entries[index].Mask = &mask
+	h.saved = &Edit{}
+	h.version++
+	h.mu.Unlock()
+
+	h.changed()
+}
+
+// SetCrop changes the crop of the entry at index, nil uses the whole image.
+// like SetMask it can not be undone.
+func (h *History) SetCrop(index int, crop *image.Rectangle) {
+	h.mu.Lock()
+	h.p.Entries[index].Crop = crop
+	h.saved = &Edit{}
+	h.version++
+	h.mu.Unlock()
+
+	h.changed()
+}
+
+// Dirty reports if there were edits since the last save
+func (h *History) Dirty() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.nextundo() != h.saved
+}
+
+// MarkSaved remembers the current state as the one on disk
+func (h *History) MarkSaved() {
+	h.mu.Lock()
+	h.saved = h.nextundo()
+	h.mu.Unlock()
+
+	h.changed()
+}
+
+// Version changes every time the tags change
+func (h *History) Version() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.version
+}
+
+// Read runs f while no edit can happen, for reading the project from other goroutines
+func (h *History) Read(f func(p *Project)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	f(h.p)
+}
+
+func (h *History) changed() {
+	if h.OnChanged != nil {
+		h.OnChanged()
+	}
+}
+
+// Indexes are the entries this edit changed
+func (e *Edit) Indexes() []int {
+	indexes := make([]int, len(e.changes))
+	for i, c := range e.changes {
+		indexes[i] = c.index
+	}
+	return indexes
+}
+
+func (e *Edit) apply(p *Project) {
+	for _, c := range e.changes {
+		p.Entries[c.index].Tags = slices.Clone(c.after)
+	}
+}
+
+func (e *Edit) revert(p *Project) {
+	// backwards in case an entry shows up more than once
+	for i := len(e.changes) - 1; i >= 0; i-- {
+		c := e.changes[i]
+		p.Entries[c.index].Tags = slices.Clone(c.before)
+	}
+}
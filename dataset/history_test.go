@@ -0,0 +1,121 @@
+package dataset
+
+import (
+	"errors"
+	"image"
+	"slices"
+	"testing"
+)
+
+func newtestproject() *Project {
+	return &Project{Entries: []Entry{
+		{ImagePath: "/a.png", Tags: []string{"a"}},
+		{ImagePath: "/b.png", Tags: []string{"b"}},
+		{ImagePath: "/c.png", Tags: []string{"c"}},
+	}}
+}
+
+func TestHistoryUndoRedo(t *testing.T) {
+	p := newtestproject()
+	h := NewHistory(p)
+	if h.Dirty() {
+		t.Fatal("a new history is dirty")
+	}
+
+	changed, err := h.Do("add x", []int{0, 1}, func(e *Entry) error { return e.AddTag("x") })
+	if err != nil || !changed {
+		t.Fatalf("Do = %v, %v", changed, err)
+	}
+	if !h.Dirty() {
+		t.Error("not dirty after an edit")
+	}
+	// an edit that changes nothing is not recorded
+	changed, _ = h.Do("add x again", []int{0}, func(e *Entry) error { return e.AddTag("x") })
+	if changed || h.NextUndo().Name != "add x" {
+		t.Error("an edit without changes was recorded")
+	}
+
+	h.MarkSaved()
+	if h.Dirty() {
+		t.Error("dirty after MarkSaved")
+	}
+
+	edit := h.Undo()
+	if edit == nil || !slices.Equal(edit.Indexes(), []int{0, 1}) {
+		t.Fatalf("Undo = %+v", edit)
+	}
+	if !slices.Equal(p.Entries[0].Tags, []string{"a"}) || !slices.Equal(p.Entries[1].Tags, []string{"b"}) {
+		t.Errorf("Undo left %q and %q", p.Entries[0].Tags, p.Entries[1].Tags)
+	}
+	if !h.Dirty() {
+		t.Error("undoing past the save is not dirty")
+	}
+
+	h.Redo()
+	if !slices.Equal(p.Entries[1].Tags, []string{"b", "x"}) || h.Dirty() {
+		t.Errorf("Redo left %q, dirty %v", p.Entries[1].Tags, h.Dirty())
+	}
+	if h.Redo() != nil {
+		t.Error("Redo with nothing to redo")
+	}
+
+	// a new edit drops what could be redone
+	h.Undo()
+	h.Do("add y", []int{2}, func(e *Entry) error { return e.AddTag("y") })
+	if h.NextRedo() != nil {
+		t.Error("redo survived a new edit")
+	}
+}
+
+func TestHistoryFailedEdit(t *testing.T) {
+	p := newtestproject()
+	h := NewHistory(p)
+	failed := errors.New("failed")
+	changed, err := h.Do("half", []int{0, 1}, func(e *Entry) error {
+		if e.ImagePath == "/b.png" {
+			return failed
+		}
+		return e.AddTag("x")
+	})
+	if changed || !errors.Is(err, failed) {
+		t.Fatalf("Do = %v, %v", changed, err)
+	}
+	if !slices.Equal(p.Entries[0].Tags, []string{"a"}) {
+		t.Errorf("a failed edit changed the first entry to %q", p.Entries[0].Tags)
+	}
+	if h.Dirty() || h.NextUndo() != nil {
+		t.Error("a failed edit was recorded")
+	}
+}
+
+func TestHistoryWithoutUndo(t *testing.T) {
+	p := newtestproject()
+	h := NewHistory(p)
+	calls := 0
+	h.OnChanged = func() { calls++ }
+
+	crop := image.Rect(0, 0, 1, 1)
+	h.SetCrop(1, &crop)
+	if p.Entries[1].Crop != &crop || !h.Dirty() {
+		t.Error("SetCrop did not change the entry or mark it dirty")
+	}
+	h.MarkSaved()
+
+	h.SetMask(0, "/a_mask.png")
+	if *p.Entries[0].Mask != "/a_mask.png" || !h.Dirty() {
+		t.Error("SetMask did not change the entry or mark it dirty")
+	}
+	h.MarkSaved()
+
+	h.Do("add x", []int{2}, func(e *Entry) error { return e.AddTag("x") })
+	h.Remove([]int{0, 2})
+	if len(p.Entries) != 1 || p.Entries[0].ImagePath != "/b.png" {
+		t.Errorf("Remove left %+v", p.Entries)
+	}
+	if h.NextUndo() != nil || !h.Dirty() {
+		t.Error("Remove kept the history or was not dirty")
+	}
+	if calls != 6 {
+		t.Errorf("OnChanged was called %d times, want 6", calls)
+	}
+}
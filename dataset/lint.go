@@ -0,0 +1,175 @@
+package dataset
+
+import (
+	"image"
+	"os"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// LintOptions are the limits an image has to stay within to be useful for training
+type LintOptions struct {
+	// the shorter side has to be at least this long
+	MinSize int
+	// the variance of the laplacian, blurry images have little
+	MinSharpness float64
+	// how much stronger the edges on the 8x8 jpeg grid are than the others, 1 is no blocks at all
+	MaxBlockiness float64
+	// in bytes, a tiny file for its size is usually compressed to death
+	MinFileSize int64
+}
+
+// DefaultLintOptions are a starting point for sd1.5 and sdxl datasets
+var DefaultLintOptions = LintOptions{MinSize: 512, MinSharpness: 100, MaxBlockiness: 1.5, MinFileSize: 20 << 10}
+
+// LintIssue is one reason an image might hurt training
+type LintIssue int
+
+const (
+	LintTooSmall LintIssue = iota
+	LintBlurry
+	LintArtefacts
+	LintTinyFile
+)
+
+func (i LintIssue) String() string {
+	switch i {
+	case LintTooSmall:
+		return "too small"
+	case LintBlurry:
+		return "blurry"
+	case LintArtefacts:
+		return "jpeg artefacts"
+	default:
+		return "tiny file"
+	}
+}
+
+// LintResult are the measurements of one image and what they failed
+type LintResult struct {
+	Width, Height int
+	Sharpness     float64
+	// zero for formats that are not made of 8x8 blocks
+	Blockiness float64
+	FileSize   int64
+	Issues     []LintIssue
+}
+
+func (r LintResult) String() string {
+	issues := make([]string, len(r.Issues))
+	for i, issue := range r.Issues {
+		issues[i] = issue.String()
+	}
+	return strings.Join(issues, ", ")
+}
+
+// LintImage decodes the image at path and checks it against o.
+// like image.Decode the formats have to be registered by the program.
+func LintImage(path string, o LintOptions) (LintResult, error) {
+	var r LintResult
+	f, err := os.Open(path)
+	if err != nil {
+		return r, &Error{Op: "read", Path: path, Err: err}
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return r, &Error{Op: "read", Path: path, Err: err}
+	}
+	img, format, err := image.Decode(f)
+	if err != nil {
+		return r, &Error{Op: "decode", Path: path, Err: err}
+	}
+
+	r.Width, r.Height = img.Bounds().Dx(), img.Bounds().Dy()
+	r.FileSize = fi.Size()
+	r.Sharpness = sharpness(img)
+	if format == "jpeg" {
+		r.Blockiness = blockiness(img)
+	}
+
+	if min(r.Width, r.Height) < o.MinSize {
+		r.Issues = append(r.Issues, LintTooSmall)
+	}
+	if r.Sharpness < o.MinSharpness {
+		r.Issues = append(r.Issues, LintBlurry)
+	}
+	if o.MaxBlockiness > 0 && r.Blockiness > o.MaxBlockiness {
+		r.Issues = append(r.Issues, LintArtefacts)
+	}
+	if r.FileSize < o.MinFileSize {
+		r.Issues = append(r.Issues, LintTinyFile)
+	}
+	return r, nil
+}
+
+// sharpness is the variance of the laplacian.
+// big images are looked at in 1024 so the numbers are comparable.
+func sharpness(img image.Image) float64 {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if longest := max(w, h); longest > 1024 {
+		w, h = max(w*1024/longest, 1), max(h*1024/longest, 1)
+	}
+	if w < 3 || h < 3 {
+		return 0
+	}
+	g := grayscale(img, w, h)
+
+	var sum, sumsq float64
+	for y := 1; y < h-1; y++ {
+		for x := 1; x < w-1; x++ {
+			i := y*g.Stride + x
+			l := float64(g.Pix[i-1]) + float64(g.Pix[i+1]) + float64(g.Pix[i-g.Stride]) + float64(g.Pix[i+g.Stride]) - 4*float64(g.Pix[i])
+			sum += l
+			sumsq += l * l
+		}
+	}
+	n := float64((w - 2) * (h - 2))
+	mean := sum / n
+	return sumsq/n - mean*mean
+}
+
+// blockiness compares the steps across the 8x8 block borders to the ones inside the blocks.
+// it needs the pixels as they were stored, scaling would hide the grid.
+func blockiness(img image.Image) float64 {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w < 16 || h < 16 {
+		return 0
+	}
+	g := image.NewGray(image.Rect(0, 0, w, h))
+	draw.Draw(g, g.Bounds(), img, b.Min, draw.Src)
+
+	var border, inside float64
+	var nborder, ninside int
+	step := func(a, b uint8, onborder bool) {
+		d := float64(a) - float64(b)
+		if d < 0 {
+			d = -d
+		}
+		if onborder {
+			border += d
+			nborder++
+		} else {
+			inside += d
+			ninside++
+		}
+	}
+	for y := range h {
+		row := g.Pix[y*g.Stride:]
+		for x := range w - 1 {
+			step(row[x], row[x+1], (x+1)%8 == 0)
+		}
+	}
+	for y := range h - 1 {
+		row, next := g.Pix[y*g.Stride:], g.Pix[(y+1)*g.Stride:]
+		onborder := (y+1)%8 == 0
+		for x := range w {
+			step(row[x], next[x], onborder)
+		}
+	}
+
+	// the one keeps flat images at 1 instead of dividing by nothing
+	return (border/float64(nborder) + 1) / (inside/float64(ninside) + 1)
+}
@@ -0,0 +1,91 @@
+package dataset
+
+import (
+	"image"
+	"image/jpeg"
+	"image/png"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// saveimage writes img to path as png or jpeg with quality q
+func saveimage(t *testing.T, path string, img image.Image, q int) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if q > 0 {
+		err = jpeg.Encode(f, img, &jpeg.Options{Quality: q})
+	} else {
+		err = png.Encode(f, img)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLintImage(t *testing.T) {
+	dir := t.TempDir()
+	noise := image.NewGray(image.Rect(0, 0, 64, 64))
+	r := rand.New(rand.NewPCG(1, 2))
+	for i := range noise.Pix {
+		noise.Pix[i] = uint8(r.IntN(256))
+	}
+	saveimage(t, filepath.Join(dir, "sharp.png"), noise, 0)
+	saveimage(t, filepath.Join(dir, "blurry.png"), testpattern(64, 48, 1), 0)
+
+	o := LintOptions{MinSize: 48, MinSharpness: 100, MaxBlockiness: 1.5}
+	sharp, err := LintImage(filepath.Join(dir, "sharp.png"), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sharp.Width != 64 || sharp.Height != 64 || sharp.Blockiness != 0 || len(sharp.Issues) != 0 {
+		t.Errorf("sharp image got %+v", sharp)
+	}
+	blurry, err := LintImage(filepath.Join(dir, "blurry.png"), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(blurry.Issues, []LintIssue{LintBlurry}) || blurry.Sharpness >= sharp.Sharpness {
+		t.Errorf("blurry image got %+v", blurry)
+	}
+
+	o = LintOptions{MinSize: 56, MinFileSize: 1 << 20}
+	small, err := LintImage(filepath.Join(dir, "blurry.png"), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(small.Issues, []LintIssue{LintTooSmall, LintTinyFile}) || small.String() != "too small, tiny file" {
+		t.Errorf("small image got %+v", small)
+	}
+
+	_, err = LintImage(filepath.Join(dir, "missing.png"), o)
+	if err == nil {
+		t.Error("a missing image has no error")
+	}
+}
+
+func TestLintBlockiness(t *testing.T) {
+	dir := t.TempDir()
+	img := testpattern(256, 256, 3)
+	saveimage(t, filepath.Join(dir, "bad.jpg"), img, 5)
+	saveimage(t, filepath.Join(dir, "good.jpg"), img, 95)
+
+	o := LintOptions{MaxBlockiness: 1.5}
+	bad, err := LintImage(filepath.Join(dir, "bad.jpg"), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	good, err := LintImage(filepath.Join(dir, "good.jpg"), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(bad.Issues, LintArtefacts) || slices.Contains(good.Issues, LintArtefacts) {
+		t.Errorf("quality 5 has blockiness %v and quality 95 %v", bad.Blockiness, good.Blockiness)
+	}
+}
@@ -0,0 +1,300 @@
+package dataset
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+type jsonlentry struct {
+	Image string  `json:"image"`
+	Text  string  `json:"text"`
+	Mask  *string `json:"mask"`
+}
+
+var (
+	DefaultImageExtensions   = []string{".png", ".jpg", ".jpeg", ".webp", ".gif", ".bmp", ".tif", ".tiff"}
+	DefaultCaptionExtensions = []string{".txt"}
+)
+
+// LoadOptions changes how a folder is loaded
+type LoadOptions struct {
+	// also load the images in subfolders, like the kohya img/10_name/ layout
+	Recursive bool
+	// lower case with the dot, nil means the defaults.
+	// when an image has more than one caption file the extension listed first wins.
+	ImageExtensions   []string
+	CaptionExtensions []string
+}
+
+// ParseExtensions reads a list like "png, .JPG webp" into lower case extensions with a dot
+func ParseExtensions(s string) []string {
+	var exts []string
+	for _, ext := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		ext = "." + strings.ToLower(strings.TrimLeft(ext, "."))
+		if ext != "." && !slices.Contains(exts, ext) {
+			exts = append(exts, ext)
+		}
+	}
+	return exts
+}
+
+// Open loads a metadata.jsonl, any other .jsonl file or a folder depending on what path is
+func Open(path string, opts LoadOptions) (*Project, []error, error) {
+	switch {
+	case filepath.Base(path) == MetadataName:
+		return LoadMetadata(path)
+	case filepath.Ext(path) == ".jsonl":
+		return LoadJSONL(path)
+	default:
+		return LoadDir(path, opts)
+	}
+}
+
+// Load pairs up the images in dir with their .txt tag files.
+// problems are files that got skipped, err means nothing could be loaded.
+func Load(dir string) (project *Project, problems []error, err error) {
+	return LoadDir(dir, LoadOptions{})
+}
+
+// LoadDir is Load with options
+func LoadDir(dir string, opts LoadOptions) (project *Project, problems []error, err error) {
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var files []string
+	if opts.Recursive {
+		files, err = filesintree(dir)
+	} else {
+		files, err = filesindir(dir)
+	}
+	if err != nil {
+		return nil, nil, &Error{Op: "list", Path: dir, Err: err}
+	}
+
+	imageexts := opts.ImageExtensions
+	if len(imageexts) < 1 {
+		imageexts = DefaultImageExtensions
+	}
+	captionexts := opts.CaptionExtensions
+	if len(captionexts) < 1 {
+		captionexts = DefaultCaptionExtensions
+	}
+
+	entries := make(map[string]Entry)
+	// which caption file each entry got its tags from
+	captions := make(map[string]string)
+
+	for _, file := range files {
+		// filter filenames
+		extension := filepath.Ext(file)
+		lower := strings.ToLower(extension)
+		iscaption := slices.Contains(captionexts, lower)
+		if !iscaption && !slices.Contains(imageexts, lower) {
+			problems = append(problems, &Error{Op: "load", Path: file, Err: ErrUnknownExtension})
+			continue
+		}
+
+		key := strings.TrimSuffix(file, extension)
+		knowndata := entries[key]
+
+		if iscaption {
+			if other, ok := captions[key]; ok {
+				// keep the one whose extension is listed first
+				if slices.Index(captionexts, strings.ToLower(filepath.Ext(other))) < slices.Index(captionexts, lower) {
+					problems = append(problems, &Error{Op: "load", Path: file, Err: ErrDuplicateCaption})
+					continue
+				}
+				problems = append(problems, &Error{Op: "load", Path: other, Err: ErrDuplicateCaption})
+			}
+			content, err := os.Open(file)
+			if err != nil {
+				problems = append(problems, &Error{Op: "read", Path: file, Err: err})
+				continue
+			}
+			knowndata.Tags = ParseTags(content)
+			content.Close()
+			captions[key] = file
+		} else {
+			if knowndata.ImagePath != "" {
+				problems = append(problems, &Error{Op: "load", Path: file, Err: ErrDuplicateImage})
+				continue
+			}
+			knowndata.ImagePath = file
+		}
+
+		entries[key] = knowndata
+	}
+
+	findmasks(entries)
+
+	project = &Project{Source: dir, Dir: dir, CaptionExtension: captionexts[0]}
+	for k, v := range entries {
+		if v.ImagePath == "" {
+			problems = append(problems, &Error{Op: "load", Path: captions[k], Err: ErrNoImage})
+			continue
+		}
+		project.Entries = append(project.Entries, v)
+	}
+	if len(project.Entries) < 1 {
+		return nil, problems, &Error{Op: "load", Path: dir, Err: ErrNothingUseable}
+	}
+	project.sort()
+
+	return project, problems, nil
+}
+
+// LoadJSONL reads a jsonl file where every line is an image with its caption
+func LoadJSONL(path string) (project *Project, problems []error, err error) {
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, &Error{Op: "read", Path: path, Err: err}
+	}
+	defer f.Close()
+
+	project = &Project{Source: path, Dir: filepath.Dir(path)}
+
+	entries := bufio.NewScanner(f)
+	// extra fields can make lines long
+	entries.Buffer(nil, 16*1024*1024)
+	line := 0
+	for entries.Scan() {
+		line++
+		if len(bytes.TrimSpace(entries.Bytes())) < 1 {
+			continue
+		}
+		var jsonlline jsonlentry
+		err := json.Unmarshal(entries.Bytes(), &jsonlline)
+		if err != nil {
+			return nil, problems, &Error{Op: "parse", Path: path, Err: fmt.Errorf("line %d: %w", line, err)}
+		}
+		// everything else is kept as it is
+		var extra map[string]json.RawMessage
+		err = json.Unmarshal(entries.Bytes(), &extra)
+		if err != nil {
+			return nil, problems, &Error{Op: "parse", Path: path, Err: fmt.Errorf("line %d: %w", line, err)}
+		}
+		delete(extra, "image")
+		delete(extra, "text")
+		delete(extra, "mask")
+		crop := takecrop(extra)
+		if len(extra) < 1 {
+			extra = nil
+		}
+
+		if jsonlline.Mask != nil {
+			mask := resolvepath(project.Dir, *jsonlline.Mask)
+			jsonlline.Mask = &mask
+		}
+		project.Entries = append(project.Entries, Entry{
+			ImagePath: resolvepath(project.Dir, jsonlline.Image),
+			Tags:      ParseTags(strings.NewReader(jsonlline.Text)),
+			Mask:      jsonlline.Mask,
+			Extra:     extra,
+			Crop:      crop,
+		})
+	}
+	if err := entries.Err(); err != nil {
+		return nil, problems, &Error{Op: "read", Path: path, Err: err}
+	}
+
+	if len(project.Entries) < 1 {
+		return nil, problems, &Error{Op: "load", Path: path, Err: ErrNothingUseable}
+	}
+	project.sort()
+
+	return project, problems, nil
+}
+
+// resolvepath makes a relative path from a jsonl absolute, they are relative to its folder
+func resolvepath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, filepath.FromSlash(path))
+}
+
+// ParseTags reads a comma separated caption, duplicates are dropped
+func ParseTags(r io.Reader) []string {
+	s := bufio.NewScanner(r)
+	var tags []string
+	for s.Scan() {
+		for _, tag := range strings.Split(s.Text(), ",") {
+			trimmed := strings.TrimSpace(tag)
+			if trimmed == "" {
+				continue
+			}
+			tags = append(tags, trimmed)
+		}
+	}
+
+	final := make([]string, 0, len(tags))
+	// remove dupes
+	foundtags := make(map[string]struct{})
+	for _, tag := range tags {
+		_, exists := foundtags[tag]
+		if !exists {
+			foundtags[tag] = struct{}{}
+			final = append(final, tag)
+		}
+	}
+
+	return final
+}
+
+func filesindir(dir string) ([]string, error) {
+	items, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(items))
+	for _, item := range items {
+		if item.Type().IsRegular() {
+			files = append(files, filepath.Join(dir, item.Name()))
+		}
+	}
+	return files, nil
+}
+
+// filesintree is filesindir for dir and all folders below it, hidden and mask folders are left out
+func filesintree(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// masks are picked up with their images
+			if path != dir && (strings.HasPrefix(d.Name(), ".") || d.Name() == MaskDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+func (p *Project) sort() {
+	slices.SortFunc(p.Entries, func(a, b Entry) int {
+		return strings.Compare(a.ImagePath, b.ImagePath)
+	})
+}
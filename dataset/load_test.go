@@ -0,0 +1,157 @@
+package dataset
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadDirAndSaveTxt(t *testing.T) {
+	dir := t.TempDir()
+	writepng(t, filepath.Join(dir, "a.png"), 4, 4, color.White)
+	writepng(t, filepath.Join(dir, "b.png"), 4, 4, color.Black)
+	writepng(t, filepath.Join(dir, "sub", "c.png"), 4, 4, color.Black)
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("x, y"), 0o644)
+	// written by another tool, the same tags but not the same bytes
+	os.WriteFile(filepath.Join(dir, "sub", "c.txt"), []byte("z,\n"), 0o644)
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	os.Chtimes(filepath.Join(dir, "sub", "c.txt"), old, old)
+
+	p, _, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Entries) != 2 {
+		t.Fatalf("without subfolders got %d entries, want 2", len(p.Entries))
+	}
+
+	p, _, err = LoadDir(dir, LoadOptions{Recursive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags := make(map[string][]string)
+	for _, e := range p.Entries {
+		tags[p.RelPath(&e)] = e.Tags
+	}
+	if len(tags) != 3 || !slices.Equal(tags["a.png"], []string{"x", "y"}) || len(tags["b.png"]) != 0 ||
+		!slices.Equal(tags[filepath.Join("sub", "c.png")], []string{"z"}) {
+		t.Fatalf("loaded %q", tags)
+	}
+
+	p.Entries[0].AddTag("new")
+	summary, err := SaveTxt(p, TxtOptions{SkipUntagged: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary != (TxtSummary{Written: 1, Unchanged: 1, Skipped: 1}) {
+		t.Errorf("SaveTxt = %+v", summary)
+	}
+	caption, _ := os.ReadFile(filepath.Join(dir, "a.txt"))
+	if string(caption) != "x, y, new" {
+		t.Errorf("a.txt = %q", caption)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.txt")); err == nil {
+		t.Error("an untagged caption file was written with SkipUntagged")
+	}
+	// an unchanged caption file is left alone
+	fi, err := os.Stat(filepath.Join(dir, "sub", "c.txt"))
+	if err != nil || !fi.ModTime().Equal(old) {
+		t.Errorf("sub/c.txt was rewritten: %v", err)
+	}
+
+	summary, err = SaveTxt(p, TxtOptions{})
+	if err != nil || summary != (TxtSummary{Written: 1, Unchanged: 2}) {
+		t.Errorf("SaveTxt without skipping = %+v, %v", summary, err)
+	}
+	caption, err = os.ReadFile(filepath.Join(dir, "b.txt"))
+	if err != nil || len(caption) != 0 {
+		t.Errorf("b.txt = %q, %v", caption, err)
+	}
+}
+
+func TestJSONLRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "in.jsonl")
+	in := `{"image":"a.png","text":"x, y","mask":"a_mask.png","crop":[1,2,30,40],"score":0.5}
+{"image":"b.png","text":"z","crop":"center","tags":{"nested":[1,2]}}
+`
+	os.WriteFile(path, []byte(in), 0o644)
+
+	p, _, err := LoadJSONL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := p.Entries[0], p.Entries[1]
+	if a.ImagePath != filepath.Join(dir, "a.png") || *a.Mask != filepath.Join(dir, "a_mask.png") {
+		t.Errorf("paths are not resolved against the jsonl: %q %q", a.ImagePath, *a.Mask)
+	}
+	if a.Crop == nil || *a.Crop != image.Rect(1, 2, 30, 40) {
+		t.Errorf("crop = %v", a.Crop)
+	}
+	if _, ok := a.Extra["crop"]; ok {
+		t.Error("a crop that was read is kept in Extra too")
+	}
+	// a crop column of another tool is just another field
+	if b.Crop != nil || string(b.Extra["crop"]) != `"center"` {
+		t.Errorf("foreign crop = %v, extra %s", b.Crop, b.Extra["crop"])
+	}
+
+	out := filepath.Join(dir, "out.jsonl")
+	err = SaveJSONL(p, out, JSONLOptions{RelativePaths: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	written, _ := os.ReadFile(out)
+	want := `{"image":"a.png","text":"x, y","mask":"a_mask.png","crop":[1,2,30,40],"score":0.5}
+{"image":"b.png","text":"z","mask":null,"crop":"center","tags":{"nested":[1,2]}}
+`
+	if string(written) != want {
+		t.Errorf("SaveJSONL wrote\n%s\nwant\n%s", written, want)
+	}
+
+	// a crop drawn later wins over the foreign one
+	crop := image.Rect(0, 0, 2, 2)
+	p.Entries[1].Crop = &crop
+	SaveJSONL(p, out, JSONLOptions{RelativePaths: true})
+	again, _, err := LoadJSONL(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := again.Entries[1].Crop; c == nil || *c != crop {
+		t.Errorf("crop after save = %v", c)
+	}
+}
+
+func TestLoadJSONLLines(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "in.jsonl")
+	long := strings.Repeat("x", 70<<10)
+	in := "\n" + `{"image":"a.png","text":"a","long":"` + long + `"}` + "\n  \n" + `{"image":"b.png","text":"b"}` + "\n\n"
+	os.WriteFile(path, []byte(in), 0o644)
+
+	p, _, err := LoadJSONL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Entries) != 2 || len(p.Entries[0].Extra["long"]) != len(long)+2 {
+		t.Errorf("got %d entries", len(p.Entries))
+	}
+
+	os.WriteFile(path, []byte(`{"image":"a.png"`+"\n"), 0o644)
+	_, _, err = LoadJSONL(path)
+	if err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("broken line gave %v", err)
+	}
+}
+
+func TestParseExtensions(t *testing.T) {
+	got := ParseExtensions("png, .JPG webp,png")
+	if want := []string{".png", ".jpg", ".webp"}; !slices.Equal(got, want) {
+		t.Errorf("ParseExtensions = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,62 @@
+package dataset
+
+import (
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// masks are found next to the image as name_mask.png or in a masks folder as masks/name.png
+const (
+	MaskSuffix = "_mask"
+	MaskDir    = "masks"
+)
+
+// MaskPath is where the mask of the entry is, or where a new one gets saved to
+func (p *Project) MaskPath(e *Entry) string {
+	if e.Mask != nil && *e.Mask != "" {
+		return *e.Mask
+	}
+	return strings.TrimSuffix(e.ImagePath, filepath.Ext(e.ImagePath)) + MaskSuffix + ".png"
+}
+
+// SaveMask writes the mask as a png, white is what the mask covers
+func SaveMask(path string, mask image.Image) error {
+	return writeatomic(path, func(w io.Writer) error {
+		return png.Encode(w, mask)
+	})
+}
+
+// findmasks takes the masks out of the loaded files and gives them to their images.
+// entries are keyed by path without extension.
+func findmasks(entries map[string]Entry) {
+	for key, e := range entries {
+		base, ok := strings.CutSuffix(key, MaskSuffix)
+		if !ok || e.ImagePath == "" {
+			continue
+		}
+		owner, ok := entries[base]
+		if !ok || owner.ImagePath == "" {
+			continue
+		}
+		mask := e.ImagePath
+		owner.Mask = &mask
+		entries[base] = owner
+		delete(entries, key)
+	}
+
+	for key, e := range entries {
+		if e.Mask != nil || e.ImagePath == "" {
+			continue
+		}
+		mask := filepath.Join(filepath.Dir(key), MaskDir, filepath.Base(key)+".png")
+		fi, err := os.Stat(mask)
+		if err == nil && fi.Mode().IsRegular() {
+			e.Mask = &mask
+			entries[key] = e
+		}
+	}
+}
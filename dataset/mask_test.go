@@ -0,0 +1,81 @@
+package dataset
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindMasks(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.png", "a_mask.png", "b.png", filepath.Join(MaskDir, "b.png"), "c.png", "d_mask.png"} {
+		writepng(t, filepath.Join(dir, name), 4, 4, color.White)
+	}
+
+	p, _, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	masks := make(map[string]string)
+	for _, e := range p.Entries {
+		masks[filepath.Base(e.ImagePath)] = ""
+		if e.Mask != nil {
+			masks[filepath.Base(e.ImagePath)] = *e.Mask
+		}
+	}
+	want := map[string]string{
+		"a.png": filepath.Join(dir, "a_mask.png"),
+		"b.png": filepath.Join(dir, MaskDir, "b.png"),
+		"c.png": "",
+		// a mask without its image is just an image
+		"d_mask.png": "",
+	}
+	if len(masks) != len(want) {
+		t.Fatalf("loaded %q, want %q", masks, want)
+	}
+	for name, mask := range want {
+		if masks[name] != mask {
+			t.Errorf("mask of %s is %q, want %q", name, masks[name], mask)
+		}
+	}
+}
+
+func TestSaveMask(t *testing.T) {
+	dir := t.TempDir()
+	p := &Project{Dir: dir}
+	e := &Entry{ImagePath: filepath.Join(dir, "a.png")}
+	path := p.MaskPath(e)
+	if path != filepath.Join(dir, "a_mask.png") {
+		t.Errorf("MaskPath = %q", path)
+	}
+
+	mask := image.NewGray(image.Rect(0, 0, 4, 4))
+	mask.SetGray(1, 2, color.Gray{255})
+	err := SaveMask(path, mask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != mask.Bounds() || color.GrayModel.Convert(img.At(1, 2)) != (color.Gray{255}) ||
+		color.GrayModel.Convert(img.At(0, 0)) != (color.Gray{0}) {
+		t.Error("the mask did not survive saving")
+	}
+
+	// a mask that was found is saved back over itself
+	found := filepath.Join(dir, MaskDir, "a.png")
+	e.Mask = &found
+	if p.MaskPath(e) != found {
+		t.Errorf("MaskPath = %q, want %q", p.MaskPath(e), found)
+	}
+}
@@ -0,0 +1,205 @@
+package dataset
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// MetadataName is the file the Hugging Face imagefolder loader looks for
+const MetadataName = "metadata.jsonl"
+
+// the columns of metadata.jsonl that are not kept in Extra
+const (
+	metadatafile    = "file_name"
+	metadatacaption = "text"
+)
+
+var ErrOutsideFolder = errors.New("is not inside the dataset folder")
+
+// MetadataPath is where metadata.jsonl gets saved to by default
+func (p *Project) MetadataPath() string {
+	return filepath.Join(p.Dir, MetadataName)
+}
+
+// LoadMetadata reads a Hugging Face imagefolder metadata.jsonl.
+// file_name is relative to the folder of the file and text is the caption,
+// every other column ends up in Entry.Extra.
+// masks are looked for next to the images like Load does.
+func LoadMetadata(path string) (project *Project, problems []error, err error) {
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, &Error{Op: "read", Path: path, Err: err}
+	}
+	defer f.Close()
+
+	project = &Project{Source: path, Dir: filepath.Dir(path)}
+
+	rows := bufio.NewScanner(f)
+	rows.Buffer(nil, 16*1024*1024)
+	line := 0
+	for rows.Scan() {
+		line++
+		if len(bytes.TrimSpace(rows.Bytes())) < 1 {
+			continue
+		}
+		var columns map[string]json.RawMessage
+		err := json.Unmarshal(rows.Bytes(), &columns)
+		if err != nil {
+			return nil, problems, &Error{Op: "parse", Path: path, Err: fmt.Errorf("line %d: %w", line, err)}
+		}
+
+		var filename, caption string
+		if raw, ok := columns[metadatafile]; ok {
+			err = json.Unmarshal(raw, &filename)
+		}
+		if raw, ok := columns[metadatacaption]; ok && err == nil {
+			err = json.Unmarshal(raw, &caption)
+		}
+		if err != nil {
+			return nil, problems, &Error{Op: "parse", Path: path, Err: fmt.Errorf("line %d: %w", line, err)}
+		}
+		if filename == "" {
+			problems = append(problems, &Error{Op: "load", Path: path, Err: fmt.Errorf("line %d: %w", line, ErrNoImage)})
+			continue
+		}
+		crop := takecrop(columns)
+		delete(columns, metadatafile)
+		delete(columns, metadatacaption)
+		if len(columns) < 1 {
+			columns = nil
+		}
+
+		project.Entries = append(project.Entries, Entry{
+			ImagePath: filepath.Join(project.Dir, filepath.FromSlash(filename)),
+			Tags:      ParseTags(strings.NewReader(caption)),
+			Extra:     columns,
+			Crop:      crop,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, problems, &Error{Op: "read", Path: path, Err: err}
+	}
+
+	if len(project.Entries) < 1 {
+		return nil, problems, &Error{Op: "load", Path: path, Err: ErrNothingUseable}
+	}
+
+	// masks are found like in a folder, a listed mask is taken out of the rows
+	entries := make(map[string]Entry, len(project.Entries))
+	var samename []Entry
+	for _, e := range project.Entries {
+		key := strings.TrimSuffix(e.ImagePath, filepath.Ext(e.ImagePath))
+		if _, ok := entries[key]; ok {
+			samename = append(samename, e)
+			continue
+		}
+		entries[key] = e
+	}
+	findmasks(entries)
+	project.Entries = append(slices.Collect(maps.Values(entries)), samename...)
+	project.sort()
+
+	return project, problems, nil
+}
+
+// SaveMetadata writes a metadata.jsonl with file_name relative to the folder of path.
+// images outside of that folder can not be listed and are reported.
+func SaveMetadata(p *Project, path string) error {
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	err = writeatomic(path, func(w io.Writer) error {
+		for i := range p.Entries {
+			d := &p.Entries[i]
+			rel, ok := inside(dir, d.ImagePath)
+			if !ok {
+				errs = append(errs, &Error{Op: "encode", Path: d.ImagePath, Err: ErrOutsideFolder})
+				continue
+			}
+
+			columns := []column{
+				{metadatafile, filepath.ToSlash(rel)},
+				{metadatacaption, p.Caption(d)},
+			}
+			if d.Crop != nil {
+				columns = append(columns, column{cropcolumn, cropvalue(d.Crop)})
+			}
+			row, err := encodecolumns(columns, d.Extra)
+			if err != nil {
+				errs = append(errs, &Error{Op: "encode", Path: d.ImagePath, Err: err})
+				continue
+			}
+
+			_, err = w.Write(append(row, '\n'))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return errors.Join(append(errs, err)...)
+}
+
+type column struct {
+	key   string
+	value any
+}
+
+// encodecolumns writes a json object with the known columns first and the extra ones after,
+// sorted by name so the output stays the same between saves
+func encodecolumns(known []column, extra map[string]json.RawMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	write := func(key string, value any) error {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		v, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+		return nil
+	}
+
+	for _, c := range known {
+		err := write(c.key, c.value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(extra)) {
+		if slices.ContainsFunc(known, func(c column) bool { return c.key == key }) {
+			continue
+		}
+		err := write(key, extra[key])
+		if err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
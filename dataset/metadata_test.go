@@ -0,0 +1,76 @@
+package dataset
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMetadataRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	writepng(t, filepath.Join(dir, "a.png"), 4, 4, color.White)
+	writepng(t, filepath.Join(dir, "sub", "b.png"), 4, 4, color.White)
+	path := filepath.Join(dir, MetadataName)
+	in := `{"file_name":"a.png","text":"x, y","aesthetic":6.5,"crop":[0,0,2,2]}
+
+{"file_name":"sub/b.png","text":"z","crop":{"mode":"center"}}
+{"text":"no image"}
+`
+	os.WriteFile(path, []byte(in), 0o644)
+
+	p, problems, err := LoadMetadata(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || len(p.Entries) != 2 {
+		t.Fatalf("got %d entries and problems %v", len(p.Entries), problems)
+	}
+	if c := p.Entries[0].Crop; c == nil || *c != image.Rect(0, 0, 2, 2) {
+		t.Errorf("crop = %v", c)
+	}
+	if p.Entries[1].Crop != nil || string(p.Entries[1].Extra["crop"]) != `{"mode":"center"}` {
+		t.Errorf("foreign crop = %v, extra %s", p.Entries[1].Crop, p.Entries[1].Extra["crop"])
+	}
+
+	err = SaveMetadata(p, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written, _ := os.ReadFile(path)
+	want := `{"file_name":"a.png","text":"x, y","crop":[0,0,2,2],"aesthetic":6.5}
+{"file_name":"sub/b.png","text":"z","crop":{"mode":"center"}}
+`
+	if string(written) != want {
+		t.Errorf("SaveMetadata wrote\n%s\nwant\n%s", written, want)
+	}
+}
+
+func TestMetadataMasks(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.png", "a_mask.png", "b.png", filepath.Join(MaskDir, "b.png")} {
+		writepng(t, filepath.Join(dir, name), 4, 4, color.White)
+	}
+	path := filepath.Join(dir, MetadataName)
+	in := `{"file_name":"a.png","text":"x"}
+{"file_name":"a_mask.png","text":""}
+{"file_name":"b.png","text":"y"}
+`
+	os.WriteFile(path, []byte(in), 0o644)
+
+	p, _, err := LoadMetadata(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Entries) != 2 {
+		t.Fatalf("the listed mask is still a row, got %d entries", len(p.Entries))
+	}
+	a, b := p.Entries[0], p.Entries[1]
+	if a.Mask == nil || *a.Mask != filepath.Join(dir, "a_mask.png") {
+		t.Errorf("mask of a.png is %v", a.Mask)
+	}
+	if b.Mask == nil || *b.Mask != filepath.Join(dir, MaskDir, "b.png") {
+		t.Errorf("mask of b.png is %v", b.Mask)
+	}
+}
@@ -0,0 +1,170 @@
+package dataset
+
+import (
+	"image"
+	"math"
+	"math/bits"
+	"slices"
+
+	"golang.org/x/image/draw"
+)
+
+// HashKind picks one of the perceptual hashes
+type HashKind int
+
+const (
+	// average hash, quick but easily fooled by changes in brightness
+	AHash HashKind = iota
+	// difference hash, compares neighbouring pixels
+	DHash
+	// dct hash, the most robust against re-encoding and small edits
+	PHash
+)
+
+func (k HashKind) String() string {
+	switch k {
+	case AHash:
+		return "aHash"
+	case DHash:
+		return "dHash"
+	default:
+		return "pHash"
+	}
+}
+
+// Hashes are the perceptual hashes of one image, similar images have hashes with few differing bits
+type Hashes [3]uint64
+
+// HashImage computes all hashes of img
+func HashImage(img image.Image) Hashes {
+	var h Hashes
+	h[AHash] = ahash(grayscale(img, 8, 8))
+	h[DHash] = dhash(grayscale(img, 9, 8))
+	h[PHash] = phash(grayscale(img, 32, 32))
+	return h
+}
+
+// Distance is how many bits of the hash differ
+func (h Hashes) Distance(other Hashes, kind HashKind) int {
+	return bits.OnesCount64(h[kind] ^ other[kind])
+}
+
+func grayscale(img image.Image, w, h int) *image.Gray {
+	small := image.NewGray(image.Rect(0, 0, w, h))
+	draw.ApproxBiLinear.Scale(small, small.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return small
+}
+
+func ahash(g *image.Gray) uint64 {
+	sum := 0
+	for _, v := range g.Pix {
+		sum += int(v)
+	}
+	mean := sum / len(g.Pix)
+	var hash uint64
+	for i, v := range g.Pix {
+		if int(v) > mean {
+			hash |= 1 << i
+		}
+	}
+	return hash
+}
+
+// dhash wants a 9x8 image
+func dhash(g *image.Gray) uint64 {
+	var hash uint64
+	i := 0
+	for y := range 8 {
+		for x := range 8 {
+			if g.GrayAt(x, y).Y < g.GrayAt(x+1, y).Y {
+				hash |= 1 << i
+			}
+			i++
+		}
+	}
+	return hash
+}
+
+// the cosines for the low 8 frequencies of a 32 sample dct
+var dctcos = func() (table [8][32]float64) {
+	for u := range 8 {
+		for x := range 32 {
+			table[u][x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / 64)
+		}
+	}
+	return table
+}()
+
+// phash wants a 32x32 image, only the lowest 8x8 frequencies are kept
+func phash(g *image.Gray) uint64 {
+	var coeffs [64]float64
+	for v := range 8 {
+		for u := range 8 {
+			sum := 0.0
+			for y := range 32 {
+				for x := range 32 {
+					sum += float64(g.Pix[y*g.Stride+x]) * dctcos[u][x] * dctcos[v][y]
+				}
+			}
+			coeffs[v*8+u] = sum
+		}
+	}
+
+	// the first one is the average brightness and would skew the median
+	sorted := slices.Clone(coeffs[1:])
+	slices.Sort(sorted)
+	median := (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+
+	var hash uint64
+	for i, c := range coeffs {
+		if c > median {
+			hash |= 1 << i
+		}
+	}
+	return hash
+}
+
+// GroupDuplicates puts the indexes of hashes that are at most threshold apart into groups,
+// images that are only similar through another one end up in the same group.
+// missing hashes are nil and never grouped, only groups with more than one image are returned.
+func GroupDuplicates(hashes []*Hashes, kind HashKind, threshold int) [][]int {
+	parent := make([]int, len(hashes))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i := range hashes {
+		if hashes[i] == nil {
+			continue
+		}
+		for j := i + 1; j < len(hashes); j++ {
+			if hashes[j] != nil && hashes[i].Distance(*hashes[j], kind) <= threshold {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	byroot := make(map[int][]int)
+	for i := range hashes {
+		if hashes[i] != nil {
+			root := find(i)
+			byroot[root] = append(byroot[root], i)
+		}
+	}
+	var groups [][]int
+	for _, group := range byroot {
+		if len(group) > 1 {
+			groups = append(groups, group)
+		}
+	}
+	// the order of a map is random, keep the groups in the order of the images
+	slices.SortFunc(groups, func(a, b []int) int { return a[0] - b[0] })
+	return groups
+}
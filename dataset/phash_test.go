@@ -0,0 +1,67 @@
+package dataset
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"slices"
+	"testing"
+)
+
+// testpattern is a smooth picture like a photo, seed changes what it shows
+func testpattern(w, h, seed int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			fx, fy := float64(x)/float64(w), float64(y)/float64(h)
+			v := uint8(127 + 120*math.Sin(fx*float64(seed)*3+fy*2)*math.Cos(fy*float64(seed+1)*2))
+			img.Set(x, y, color.NRGBA{v, v / 2, 255 - v, 255})
+		}
+	}
+	return img
+}
+
+func TestHashImage(t *testing.T) {
+	a := testpattern(128, 96, 1)
+	// the same image smaller and slightly brighter, like a re-encoded copy
+	copied := image.NewNRGBA(image.Rect(0, 0, 64, 48))
+	for y := range 48 {
+		for x := range 64 {
+			c := a.NRGBAAt(x*2, y*2)
+			copied.Set(x, y, color.NRGBA{min(c.R, 245) + 10, min(c.G, 245) + 10, min(c.B, 245) + 10, 255})
+		}
+	}
+	other := testpattern(128, 96, 5)
+
+	ha, hcopy, hother := HashImage(a), HashImage(copied), HashImage(other)
+	for _, kind := range []HashKind{AHash, DHash, PHash} {
+		if d := ha.Distance(ha, kind); d != 0 {
+			t.Errorf("%s of the same image is %d apart", kind, d)
+		}
+		near, far := ha.Distance(hcopy, kind), ha.Distance(hother, kind)
+		if near > 10 || near >= far {
+			t.Errorf("%s: the copy is %d apart and another image %d", kind, near, far)
+		}
+	}
+}
+
+func TestGroupDuplicates(t *testing.T) {
+	h := func(v uint64) *Hashes { return &Hashes{v, v, v} }
+	hashes := []*Hashes{
+		h(0b0000),
+		h(0b1111_0000_0000),
+		h(0b0001),
+		nil,
+		// only close to the one before, which is close to the first
+		h(0b0011),
+		h(0b1111_0000_0001),
+	}
+	got := GroupDuplicates(hashes, PHash, 1)
+	want := [][]int{{0, 2, 4}, {1, 5}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("GroupDuplicates = %v, want %v", got, want)
+	}
+	if got := GroupDuplicates(hashes, PHash, 0); len(got) != 0 {
+		t.Errorf("nothing is the same but got %v", got)
+	}
+}
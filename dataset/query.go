@@ -0,0 +1,376 @@
+package dataset
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Query filters entries with an expression like
+//
+//	1girl AND NOT (outdoors OR "night sky")
+//	untagged OR tags<3
+//	file:*_v2.png, *hair
+//
+// AND, OR and NOT have to be upper case and a comma works like AND.
+// terms are tag names, words without an operator in between belong to the same tag.
+// * and ? match any tag like a glob, quote a tag to match it exactly.
+// brackets only group at the start of a term, so pokemon (creature) is a single tag.
+// untagged, tags<N, tags>=N, tags=N..M and file:<glob> are special terms.
+// an empty query matches everything.
+type Query struct {
+	root matcher
+}
+
+// QueryError points at where the query could not be understood
+type QueryError struct {
+	Query string
+	Pos   int
+	Msg   string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("query %q at %d: %s", e.Query, e.Pos, e.Msg)
+}
+
+type matcher interface {
+	match(e *Entry) bool
+}
+
+// ParseQuery turns the expression into a Query
+func ParseQuery(s string) (*Query, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) < 1 {
+		return &Query{}, nil
+	}
+
+	qp := queryparser{query: s, tokens: tokens}
+	root, err := qp.parseor()
+	if err != nil {
+		return nil, err
+	}
+	if qp.pos < len(qp.tokens) {
+		return nil, qp.errorf("unexpected %s", qp.tokens[qp.pos].text)
+	}
+	return &Query{root: root}, nil
+}
+
+// Match reports if the entry matches
+func (q *Query) Match(e *Entry) bool {
+	if q == nil || q.root == nil {
+		return true
+	}
+	return q.root.match(e)
+}
+
+// Filter returns the indexes of the entries that match
+func (p *Project) Filter(q *Query) []int {
+	indexes := make([]int, 0, len(p.Entries))
+	for i := range p.Entries {
+		if q.Match(&p.Entries[i]) {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+type tokenkind int
+
+const (
+	tokword tokenkind = iota
+	tokquoted
+	tokand
+	tokor
+	toknot
+	tokopen
+	tokclose
+)
+
+type token struct {
+	kind tokenkind
+	text string
+	pos  int
+}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(' && len(tokens) > 0 && tokens[len(tokens)-1].kind == tokword:
+			// after a word it belongs to the tag, like pokemon (creature)
+			end := closingparen(s, i)
+			if end < 0 {
+				return nil, &QueryError{Query: s, Pos: i, Msg: "missing )"}
+			}
+			tokens = append(tokens, token{kind: tokword, text: s[i:end], pos: i})
+			i = end
+		case c == '(':
+			tokens = append(tokens, token{kind: tokopen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokclose, text: ")", pos: i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{kind: tokand, text: ",", pos: i})
+			i++
+		case c == '"':
+			end := strings.IndexByte(s[i+1:], '"')
+			if end < 0 {
+				return nil, &QueryError{Query: s, Pos: i, Msg: "missing closing quote"}
+			}
+			tokens = append(tokens, token{kind: tokquoted, text: s[i+1 : i+1+end], pos: i})
+			i += end + 2
+		default:
+			start := i
+			for i < len(s) && !strings.ContainsRune(" \t(),\"", rune(s[i])) {
+				i++
+				// tags like name_(artist) keep their brackets
+				if i < len(s) && s[i] == '(' {
+					if end := closingparen(s, i); end > 0 {
+						i = end
+					}
+				}
+			}
+			word := s[start:i]
+			kind := tokword
+			switch word {
+			case "AND":
+				kind = tokand
+			case "OR":
+				kind = tokor
+			case "NOT":
+				kind = toknot
+			}
+			tokens = append(tokens, token{kind: kind, text: word, pos: start})
+		}
+	}
+	return tokens, nil
+}
+
+// closingparen returns the index after the ) that closes the ( at i, -1 if there is none
+func closingparen(s string, i int) int {
+	depth := 0
+	for j := i; j < len(s); j++ {
+		switch s[j] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return -1
+}
+
+type queryparser struct {
+	query  string
+	tokens []token
+	pos    int
+}
+
+func (qp *queryparser) errorf(format string, args ...any) error {
+	pos := len(qp.query)
+	if qp.pos < len(qp.tokens) {
+		pos = qp.tokens[qp.pos].pos
+	}
+	return &QueryError{Query: qp.query, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (qp *queryparser) next(kind tokenkind) bool {
+	if qp.pos < len(qp.tokens) && qp.tokens[qp.pos].kind == kind {
+		qp.pos++
+		return true
+	}
+	return false
+}
+
+func (qp *queryparser) parseor() (matcher, error) {
+	left, err := qp.parseand()
+	if err != nil {
+		return nil, err
+	}
+	for qp.next(tokor) {
+		right, err := qp.parseand()
+		if err != nil {
+			return nil, err
+		}
+		left = ormatcher{left, right}
+	}
+	return left, nil
+}
+
+func (qp *queryparser) parseand() (matcher, error) {
+	left, err := qp.parsenot()
+	if err != nil {
+		return nil, err
+	}
+	for qp.next(tokand) {
+		right, err := qp.parsenot()
+		if err != nil {
+			return nil, err
+		}
+		left = andmatcher{left, right}
+	}
+	return left, nil
+}
+
+func (qp *queryparser) parsenot() (matcher, error) {
+	if qp.next(toknot) {
+		inner, err := qp.parsenot()
+		if err != nil {
+			return nil, err
+		}
+		return notmatcher{inner}, nil
+	}
+	return qp.parseterm()
+}
+
+func (qp *queryparser) parseterm() (matcher, error) {
+	if qp.next(tokopen) {
+		inner, err := qp.parseor()
+		if err != nil {
+			return nil, err
+		}
+		if !qp.next(tokclose) {
+			return nil, qp.errorf("missing )")
+		}
+		return inner, nil
+	}
+
+	if qp.pos >= len(qp.tokens) {
+		return nil, qp.errorf("expected a tag at the end")
+	}
+	tok := qp.tokens[qp.pos]
+	switch tok.kind {
+	case tokquoted:
+		qp.pos++
+		return tagmatcher(tok.text), nil
+	case tokword:
+		// words next to each other are one tag with spaces
+		var words []string
+		for qp.pos < len(qp.tokens) && qp.tokens[qp.pos].kind == tokword {
+			words = append(words, qp.tokens[qp.pos].text)
+			qp.pos++
+		}
+		return qp.special(strings.Join(words, " "), tok.pos)
+	default:
+		return nil, qp.errorf("expected a tag but got %s", tok.text)
+	}
+}
+
+var tagcountre = regexp.MustCompile(`^tags\s*(<=|>=|<|>|=)\s*(\d+)(?:\s*\.\.\s*(\d+))?$`)
+
+// special sorts out the terms that are not just a tag
+func (qp *queryparser) special(term string, pos int) (matcher, error) {
+	switch {
+	case term == "untagged":
+		return countmatcher{min: 0, max: 0}, nil
+
+	case strings.HasPrefix(term, "file:"):
+		glob := strings.TrimPrefix(term, "file:")
+		_, err := path.Match(glob, "")
+		if err != nil {
+			return nil, &QueryError{Query: qp.query, Pos: pos, Msg: "bad file glob: " + err.Error()}
+		}
+		return filematcher(glob), nil
+
+	case strings.HasPrefix(term, "tags") && strings.ContainsAny(term, "<>="):
+		m := tagcountre.FindStringSubmatch(term)
+		if m == nil {
+			return nil, &QueryError{Query: qp.query, Pos: pos, Msg: "tag count must look like tags>=3 or tags=2..5"}
+		}
+		n, _ := strconv.Atoi(m[2])
+		if m[3] != "" {
+			if m[1] != "=" {
+				return nil, &QueryError{Query: qp.query, Pos: pos, Msg: "a tag count range needs ="}
+			}
+			n2, _ := strconv.Atoi(m[3])
+			if n2 < n {
+				return nil, &QueryError{Query: qp.query, Pos: pos, Msg: "a tag count range must not end before it starts"}
+			}
+			return countmatcher{min: n, max: n2}, nil
+		}
+		switch m[1] {
+		case "<":
+			if n < 1 {
+				return nonematcher{}, nil // no entry has less than 0 tags
+			}
+			return countmatcher{min: 0, max: n - 1}, nil
+		case "<=":
+			return countmatcher{min: 0, max: n}, nil
+		case ">":
+			return countmatcher{min: n + 1, max: -1}, nil
+		case ">=":
+			return countmatcher{min: n, max: -1}, nil
+		default:
+			return countmatcher{min: n, max: n}, nil
+		}
+
+	case strings.ContainsAny(term, "*?["):
+		_, err := path.Match(term, "")
+		if err != nil {
+			return nil, &QueryError{Query: qp.query, Pos: pos, Msg: "bad tag glob: " + err.Error()}
+		}
+		return globmatcher(term), nil
+
+	default:
+		return tagmatcher(term), nil
+	}
+}
+
+type andmatcher [2]matcher
+
+func (m andmatcher) match(e *Entry) bool { return m[0].match(e) && m[1].match(e) }
+
+type ormatcher [2]matcher
+
+func (m ormatcher) match(e *Entry) bool { return m[0].match(e) || m[1].match(e) }
+
+type notmatcher [1]matcher
+
+func (m notmatcher) match(e *Entry) bool { return !m[0].match(e) }
+
+type tagmatcher string
+
+func (m tagmatcher) match(e *Entry) bool { return e.HasTag(string(m)) }
+
+type globmatcher string
+
+func (m globmatcher) match(e *Entry) bool {
+	return slices.ContainsFunc(e.Tags, func(tag string) bool {
+		ok, _ := path.Match(string(m), tag)
+		return ok
+	})
+}
+
+type filematcher string
+
+func (m filematcher) match(e *Entry) bool {
+	ok, _ := path.Match(string(m), e.Name())
+	return ok
+}
+
+type nonematcher struct{}
+
+func (nonematcher) match(*Entry) bool { return false }
+
+// max < 0 means no upper limit
+type countmatcher struct {
+	min, max int
+}
+
+func (m countmatcher) match(e *Entry) bool {
+	return len(e.Tags) >= m.min && (m.max < 0 || len(e.Tags) <= m.max)
+}
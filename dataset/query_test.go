@@ -0,0 +1,85 @@
+package dataset
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	p := &Project{Entries: []Entry{
+		{ImagePath: "/d/a.png", Tags: []string{"1girl", "long hair", "outdoors"}},
+		{ImagePath: "/d/b_v2.png", Tags: []string{"1boy", "short hair"}},
+		{ImagePath: "/d/c.jpg"},
+		{ImagePath: "/d/d.png", Tags: []string{"pokemon (creature)", "name_(artist)", "night sky"}},
+	}}
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"", []int{0, 1, 2, 3}},
+		{"1girl", []int{0}},
+		{"long hair", []int{0}},
+		{`"long hair"`, []int{0}},
+		{"*hair", []int{0, 1}},
+		{"1girl OR 1boy", []int{0, 1}},
+		{"NOT 1girl", []int{1, 2, 3}},
+		{"*hair, NOT outdoors", []int{1}},
+		{"*hair AND NOT (outdoors OR 1boy)", []int{}},
+		{"untagged", []int{2}},
+		{"untagged OR tags<3", []int{1, 2}},
+		{"tags<0", []int{}},
+		{"tags<1", []int{2}},
+		{"tags<=2", []int{1, 2}},
+		{"tags>2", []int{0, 3}},
+		{"tags>=3", []int{0, 3}},
+		{"tags=2", []int{1}},
+		{"tags=1..2", []int{1}},
+		{"tags=0..0", []int{2}},
+		{"file:*_v2.png", []int{1}},
+		{"file:*.jpg OR 1boy", []int{1, 2}},
+		{"pokemon (creature)", []int{3}},
+		{"name_(artist)", []int{3}},
+		{"(pokemon (creature) OR 1boy), NOT night sky", []int{1}},
+		{"NOT (1girl)", []int{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Errorf("ParseQuery(%q): %v", tt.query, err)
+			continue
+		}
+		got := p.Filter(q)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%q matched %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+	}{
+		{`"open`, 0},
+		{"a AND", 5},
+		{"(a OR b", 7},
+		{"a)", 1},
+		{"pokemon (creature", 8},
+		{"tags=5..2", 0},
+		{"tags<2..5", 0},
+		{"tags<x", 0},
+		{"file:[", 0},
+	}
+	for _, tt := range tests {
+		_, err := ParseQuery(tt.query)
+		var qe *QueryError
+		if !errors.As(err, &qe) {
+			t.Errorf("ParseQuery(%q) = %v, want a QueryError", tt.query, err)
+			continue
+		}
+		if qe.Pos != tt.pos {
+			t.Errorf("ParseQuery(%q) points at %d, want %d: %v", tt.query, qe.Pos, tt.pos, err)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package dataset
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Journal is a copy of the unsaved tags, crops and masks so they can be restored after a crash
+type Journal struct {
+	// the Source of the project
+	Source string    `json:"source"`
+	Time   time.Time `json:"time"`
+	// image path to tags
+	Tags map[string][]string `json:"tags"`
+	// image path to crop, images without one are not in here.
+	// nil in journals written before crops were kept.
+	Crops map[string]image.Rectangle `json:"crops"`
+	// image path to mask path
+	Masks map[string]string `json:"masks"`
+}
+
+// NewJournal copies the current tags, crops and masks of p
+func NewJournal(p *Project) *Journal {
+	j := &Journal{
+		Source: p.Source,
+		Time:   time.Now(),
+		Tags:   make(map[string][]string, len(p.Entries)),
+		Crops:  make(map[string]image.Rectangle),
+		Masks:  make(map[string]string),
+	}
+	for _, e := range p.Entries {
+		j.Tags[e.ImagePath] = slices.Clone(e.Tags)
+		if e.Crop != nil {
+			j.Crops[e.ImagePath] = *e.Crop
+		}
+		if e.Mask != nil {
+			j.Masks[e.ImagePath] = *e.Mask
+		}
+	}
+	return j
+}
+
+// Apply sets the tags the journal has for e, meant to be used with History.DoAll
+func (j *Journal) Apply(e *Entry) error {
+	tags, ok := j.Tags[e.ImagePath]
+	if ok {
+		e.Tags = slices.Clone(tags)
+	}
+	return nil
+}
+
+// Restore puts the journal back onto the project of h.
+// the tags come back as one edit that can be undone, crops and masks like they were drawn.
+func (j *Journal) Restore(h *History) error {
+	_, err := h.DoAll("restore unsaved changes", j.Apply)
+	if err != nil {
+		return err
+	}
+
+	crops := make(map[int]*image.Rectangle)
+	masks := make(map[int]string)
+	h.Read(func(p *Project) {
+		for i, e := range p.Entries {
+			if _, ok := j.Tags[e.ImagePath]; !ok {
+				// added after the journal was written
+				continue
+			}
+			crop, ok := j.Crops[e.ImagePath]
+			if j.Crops != nil && (ok != (e.Crop != nil) || ok && crop != *e.Crop) {
+				if ok {
+					crops[i] = &crop
+				} else {
+					crops[i] = nil
+				}
+			}
+			// masks can not be taken away, only replaced
+			mask, ok := j.Masks[e.ImagePath]
+			if ok && (e.Mask == nil || *e.Mask != mask) {
+				masks[i] = mask
+			}
+		}
+	})
+	for i, crop := range crops {
+		h.SetCrop(i, crop)
+	}
+	for i, mask := range masks {
+		h.SetMask(i, mask)
+	}
+	return nil
+}
+
+// JournalPath is where the journal for source lives, outside of the dataset
+func JournalPath(source string) (string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	key := sha256.Sum256([]byte(source))
+	return filepath.Join(cache, "aidatasetmanager", "recovery", hex.EncodeToString(key[:])+".json"), nil
+}
+
+// WriteJournal replaces the journal for its source
+func WriteJournal(j *Journal) error {
+	path, err := JournalPath(j.Source)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return &Error{Op: "write", Path: path, Err: err}
+	}
+
+	return writeatomic(path, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(j)
+	})
+}
+
+// ReadJournal returns the journal left behind for source.
+// the error matches os.ErrNotExist if there is none.
+func ReadJournal(source string) (*Journal, error) {
+	path, err := JournalPath(source)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, &Error{Op: "read", Path: path, Err: err}
+	}
+	defer f.Close()
+
+	var j Journal
+	err = json.NewDecoder(f).Decode(&j)
+	if err != nil {
+		return nil, &Error{Op: "parse", Path: path, Err: err}
+	}
+	if j.Source != source {
+		// someone managed a hash collision
+		return nil, &Error{Op: "read", Path: path, Err: os.ErrNotExist}
+	}
+
+	return &j, nil
+}
+
+// RemoveJournal deletes the journal for source, it is fine if there is none
+func RemoveJournal(source string) error {
+	path, err := JournalPath(source)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return &Error{Op: "remove", Path: path, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package dataset
+
+import (
+	"errors"
+	"image"
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestJournal(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	p := newtestproject()
+	p.Source = "/d"
+
+	_, err := ReadJournal(p.Source)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("ReadJournal without a journal = %v", err)
+	}
+
+	h := NewHistory(p)
+	h.Do("add x", []int{0}, func(e *Entry) error { return e.AddTag("x") })
+	err = WriteJournal(NewJournal(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the journal is restored onto a fresh load as an edit that can be undone
+	restored := newtestproject()
+	restored.Source = p.Source
+	j, err := ReadJournal(restored.Source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h = NewHistory(restored)
+	changed, err := h.DoAll("restore", j.Apply)
+	if err != nil || !changed {
+		t.Fatalf("DoAll = %v, %v", changed, err)
+	}
+	if !slices.Equal(restored.Entries[0].Tags, []string{"a", "x"}) || !slices.Equal(restored.Entries[1].Tags, []string{"b"}) {
+		t.Errorf("restored %q and %q", restored.Entries[0].Tags, restored.Entries[1].Tags)
+	}
+	if !h.Dirty() {
+		t.Error("a restored journal is not dirty")
+	}
+
+	if _, err := ReadJournal("/other"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("found a journal for another source: %v", err)
+	}
+
+	err = RemoveJournal(p.Source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadJournal(p.Source); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("the journal is still there: %v", err)
+	}
+	if err := RemoveJournal(p.Source); err != nil {
+		t.Errorf("removing a missing journal = %v", err)
+	}
+}
+
+func TestJournalCropsAndMasks(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	p := newtestproject()
+	p.Source = "/d"
+	crop := image.Rect(0, 0, 2, 2)
+	p.Entries[1].Crop = &crop
+	h := NewHistory(p)
+	drawn := image.Rect(1, 1, 3, 3)
+	h.SetCrop(0, &drawn)
+	h.SetCrop(1, nil)
+	h.SetMask(2, "/c_mask.png")
+	err := WriteJournal(NewJournal(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := newtestproject()
+	restored.Source = p.Source
+	restored.Entries[1].Crop = &crop
+	j, err := ReadJournal(restored.Source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h = NewHistory(restored)
+	err = j.Restore(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := restored.Entries
+	if e[0].Crop == nil || *e[0].Crop != drawn || e[1].Crop != nil || e[2].Mask == nil || *e[2].Mask != "/c_mask.png" {
+		t.Errorf("restored %+v", e)
+	}
+	if !h.Dirty() {
+		t.Error("restoring only crops and masks is not dirty")
+	}
+
+	// a journal from before crops were kept leaves them alone
+	j.Crops = nil
+	restored.Entries[1].Crop = &crop
+	j.Restore(h)
+	if restored.Entries[1].Crop != &crop {
+		t.Error("an old journal removed a crop")
+	}
+}
@@ -0,0 +1,102 @@
+package dataset
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"io/fs"
+	"slices"
+	"strings"
+)
+
+// categories of problems
+const (
+	CategoryUnknownExtension = "unknown extension"
+	CategoryNoImage          = "caption without image"
+	CategoryDuplicate        = "duplicate"
+	CategoryUnreadable       = "unreadable"
+	CategoryDecode           = "decode failed"
+	CategoryOther            = "other"
+)
+
+// Problem is one file that could not be used, for showing them all in one place
+type Problem struct {
+	Category string
+	Path     string
+	Reason   string
+}
+
+// NewProblem sorts an error from loading into a category
+func NewProblem(err error) Problem {
+	problem := Problem{Category: CategoryOther, Reason: err.Error()}
+	var ferr *Error
+	if errors.As(err, &ferr) {
+		problem.Path = ferr.Path
+		problem.Reason = ferr.Err.Error()
+	}
+
+	switch {
+	case errors.Is(err, ErrUnknownExtension):
+		problem.Category = CategoryUnknownExtension
+	case errors.Is(err, ErrNoImage):
+		problem.Category = CategoryNoImage
+	case errors.Is(err, ErrDuplicateImage), errors.Is(err, ErrDuplicateCaption):
+		problem.Category = CategoryDuplicate
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, fs.ErrPermission):
+		problem.Category = CategoryUnreadable
+	case ferr != nil && ferr.Op == "read":
+		problem.Category = CategoryUnreadable
+	case ferr != nil && ferr.Op == "decode":
+		problem.Category = CategoryDecode
+	}
+	return problem
+}
+
+// NewProblems is NewProblem for every error
+func NewProblems(errs []error) []Problem {
+	problems := make([]Problem, 0, len(errs))
+	for _, err := range errs {
+		problems = append(problems, NewProblem(err))
+	}
+	return problems
+}
+
+// ProblemCategories returns the categories that are used, sorted by name
+func ProblemCategories(problems []Problem) []string {
+	var categories []string
+	for _, problem := range problems {
+		if !slices.Contains(categories, problem.Category) {
+			categories = append(categories, problem.Category)
+		}
+	}
+	slices.Sort(categories)
+	return categories
+}
+
+// FilterProblems keeps the problems of the category, or all when it is empty,
+// whose path or reason contain search
+func FilterProblems(problems []Problem, category, search string) []Problem {
+	search = strings.ToLower(search)
+	var filtered []Problem
+	for _, problem := range problems {
+		if category != "" && problem.Category != category {
+			continue
+		}
+		if !strings.Contains(strings.ToLower(problem.Path), search) && !strings.Contains(strings.ToLower(problem.Reason), search) {
+			continue
+		}
+		filtered = append(filtered, problem)
+	}
+	return filtered
+}
+
+// WriteProblemsCSV exports the problems with a category,file,reason header
+func WriteProblemsCSV(w io.Writer, problems []Problem) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"category", "file", "reason"})
+	for _, problem := range problems {
+		cw.Write([]string{problem.Category, problem.Path, problem.Reason})
+	}
+	cw.Flush()
+	return cw.Error()
+}
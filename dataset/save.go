@@ -0,0 +1,161 @@
+package dataset
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Caption joins the tags in the order they were given
+func (e *Entry) Caption() string {
+	return strings.Join(e.Tags, ", ")
+}
+
+// Caption is what gets written to disk for the entry, pinned tags come first
+func (p *Project) Caption(e *Entry) string {
+	return strings.Join(p.OrderedTags(e), ", ")
+}
+
+// JSONLPath is where the jsonl gets saved to by default
+func (p *Project) JSONLPath() string {
+	return filepath.Join(p.Dir, filepath.Base(p.Dir)+".jsonl")
+}
+
+// TxtPath is the caption file for the entry, it sits right next to the image
+func (p *Project) TxtPath(e *Entry) string {
+	ext := p.CaptionExtension
+	if ext == "" {
+		ext = ".txt"
+	}
+	return strings.TrimSuffix(e.ImagePath, filepath.Ext(e.ImagePath)) + ext
+}
+
+// JSONLOptions changes what SaveJSONL writes
+type JSONLOptions struct {
+	// write image and mask paths relative to the jsonl file, so the dataset can be moved with it
+	RelativePaths bool
+}
+
+// SaveJSONL writes all entries into a single jsonl file at path
+func SaveJSONL(p *Project, path string, opts JSONLOptions) error {
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	// relative paths always use / so they work on other systems too
+	topath := func(abs string) string {
+		if !opts.RelativePaths {
+			return abs
+		}
+		rel, err := filepath.Rel(dir, abs)
+		if err != nil {
+			return abs
+		}
+		return filepath.ToSlash(rel)
+	}
+
+	var errs []error
+	err = writeatomic(path, func(w io.Writer) error {
+		for i := range p.Entries {
+			d := &p.Entries[i]
+			entry := jsonlentry{
+				Image: topath(d.ImagePath),
+				Text:  p.Caption(d),
+				Mask:  d.Mask,
+			}
+			if d.Mask != nil {
+				mask := topath(*d.Mask)
+				entry.Mask = &mask
+			}
+
+			columns := []column{
+				{"image", entry.Image},
+				{"text", entry.Text},
+				{"mask", entry.Mask},
+			}
+			// only written when there is one, so files without crops stay the same
+			if d.Crop != nil {
+				columns = append(columns, column{cropcolumn, cropvalue(d.Crop)})
+			}
+			str, err := encodecolumns(columns, d.Extra)
+			if err != nil {
+				errs = append(errs, &Error{Op: "encode", Path: d.ImagePath, Err: err})
+				continue
+			}
+
+			_, err = w.Write(append(str, '\n'))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return errors.Join(append(errs, err)...)
+}
+
+// TxtOptions changes what SaveTxt writes
+type TxtOptions struct {
+	// do not create caption files for images without tags, existing ones still get emptied
+	SkipUntagged bool
+}
+
+// TxtSummary counts what SaveTxt did
+type TxtSummary struct {
+	// created or changed
+	Written int
+	// already had the right tags
+	Unchanged int
+	// untagged and there was no caption file
+	Skipped int
+}
+
+func (s TxtSummary) String() string {
+	return fmt.Sprintf("%d caption files written, %d unchanged, %d skipped", s.Written, s.Unchanged, s.Skipped)
+}
+
+// SaveTxt writes a .txt file with the tags for every image.
+// files that already have the same tags are left alone so their mtime stays,
+// even if they are spaced or broken into lines differently.
+func SaveTxt(p *Project, opts TxtOptions) (TxtSummary, error) {
+	var summary TxtSummary
+	var errs []error
+	for i := range p.Entries {
+		d := &p.Entries[i]
+		txtpath := p.TxtPath(d)
+		caption := p.Caption(d)
+
+		current, err := os.ReadFile(txtpath)
+		switch {
+		case err == nil:
+			if slices.Equal(ParseTags(bytes.NewReader(current)), p.OrderedTags(d)) {
+				summary.Unchanged++
+				continue
+			}
+		case errors.Is(err, fs.ErrNotExist):
+			if caption == "" && opts.SkipUntagged {
+				summary.Skipped++
+				continue
+			}
+		default:
+			errs = append(errs, &Error{Op: "read", Path: txtpath, Err: err})
+			continue
+		}
+
+		err = writeatomic(txtpath, func(w io.Writer) error {
+			_, err := io.WriteString(w, caption)
+			return err
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		summary.Written++
+	}
+	return summary, errors.Join(errs...)
+}
@@ -0,0 +1,54 @@
+package dataset
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// TrashDir is where removed files are moved to, the loader skips it because it is hidden
+func (p *Project) TrashDir() string {
+	return filepath.Join(p.Dir, ".trash")
+}
+
+// Trash moves the image of the entry with its caption file and mask into dir,
+// they keep their place relative to the project folder.
+// if one of them can not be moved, the ones already moved are put back.
+func (p *Project) Trash(e *Entry, dir string) error {
+	files := []string{e.ImagePath, p.TxtPath(e)}
+	if e.Mask != nil {
+		files = append(files, *e.Mask)
+	}
+
+	var moved [][2]string
+	for _, file := range files {
+		rel, ok := inside(p.Dir, file)
+		if !ok {
+			rel = filepath.Base(file)
+		}
+		to := filepath.Join(dir, rel)
+
+		err := os.MkdirAll(filepath.Dir(to), 0o755)
+		if err == nil {
+			err = os.Rename(file, to)
+		}
+		if errors.Is(err, fs.ErrNotExist) && file != e.ImagePath {
+			// not every image has a caption file
+			continue
+		}
+		if err != nil {
+			errs := []error{&Error{Op: "trash", Path: file, Err: err}}
+			for _, m := range slices.Backward(moved) {
+				err := os.Rename(m[1], m[0])
+				if err != nil {
+					errs = append(errs, &Error{Op: "restore", Path: m[0], Err: err})
+				}
+			}
+			return errors.Join(errs...)
+		}
+		moved = append(moved, [2]string{file, to})
+	}
+	return nil
+}
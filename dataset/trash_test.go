@@ -0,0 +1,79 @@
+package dataset
+
+import (
+	"errors"
+	"image/color"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTrash(t *testing.T) {
+	dir := t.TempDir()
+	writepng(t, filepath.Join(dir, "sub", "a.png"), 4, 4, color.White)
+	writepng(t, filepath.Join(dir, "sub", "a_mask.png"), 4, 4, color.White)
+	os.WriteFile(filepath.Join(dir, "sub", "a.txt"), []byte("x"), 0o644)
+	writepng(t, filepath.Join(dir, "b.png"), 4, 4, color.White)
+	writepng(t, filepath.Join(dir, "c.png"), 4, 4, color.White)
+
+	p := &Project{Dir: dir}
+	mask := filepath.Join(dir, "sub", "a_mask.png")
+	a := &Entry{ImagePath: filepath.Join(dir, "sub", "a.png"), Mask: &mask}
+	err := p.Trash(a, p.TrashDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.png", "a.txt", "a_mask.png"} {
+		if _, err := os.Stat(filepath.Join(dir, "sub", name)); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("%s is still there: %v", name, err)
+		}
+		if _, err := os.Stat(filepath.Join(p.TrashDir(), "sub", name)); err != nil {
+			t.Errorf("%s is not in the trash: %v", name, err)
+		}
+	}
+
+	// an image without a caption file is fine
+	b := &Entry{ImagePath: filepath.Join(dir, "b.png")}
+	err = p.Trash(b, p.TrashDir())
+	if err != nil {
+		t.Errorf("trashing an image without a caption = %v", err)
+	}
+
+	// but an image that is gone is not
+	err = p.Trash(b, p.TrashDir())
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("trashing a missing image = %v", err)
+	}
+
+	// the loader does not see the trash
+	loaded, _, err := LoadDir(dir, LoadOptions{Recursive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Entries) != 1 {
+		t.Errorf("loaded %d entries, want only c.png", len(loaded.Entries))
+	}
+}
+
+func TestTrashRollback(t *testing.T) {
+	dir := t.TempDir()
+	writepng(t, filepath.Join(dir, "a.png"), 4, 4, color.White)
+	writepng(t, filepath.Join(dir, "a_mask.png"), 4, 4, color.White)
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("x"), 0o644)
+
+	p := &Project{Dir: dir}
+	// a folder in the way of the mask
+	os.MkdirAll(filepath.Join(p.TrashDir(), "a_mask.png", "x"), 0o755)
+
+	mask := filepath.Join(dir, "a_mask.png")
+	err := p.Trash(&Entry{ImagePath: filepath.Join(dir, "a.png"), Mask: &mask}, p.TrashDir())
+	if err == nil {
+		t.Fatal("the mask could not be moved but there is no error")
+	}
+	for _, name := range []string{"a.png", "a.txt", "a_mask.png"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s was not put back: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"biehdc.priv.aidatasetmanager/dataset"
+)
+
+// findduplicates hashes every image and shows the groups of near duplicates next to each other.
+// remove gets the indexes of the entries that should leave the project.
+func (g *gui) findduplicates(p *projectStructure, thumbsize int, remove func(ids []int)) {
+	prefs := g.a.Preferences()
+
+	// hashing works on the thumbnails, they are cached and small enough
+	hashes := make([]*dataset.Hashes, len(p.Entries))
+	index := make(map[string]int, len(p.Entries))
+	paths := make([]string, len(p.Entries))
+	for i, e := range p.Entries {
+		index[e.ImagePath] = i
+		paths[i] = e.ImagePath
+	}
+
+	kinds := []string{dataset.AHash.String(), dataset.DHash.String(), dataset.PHash.String()}
+	kind := widget.NewSelect(kinds, nil)
+	thresholdlabel := widget.NewLabel("")
+	threshold := widget.NewSlider(0, 32)
+	threshold.Step = 1
+	trash := widget.NewCheck("Move removed files to "+p.TrashDir(), func(b bool) {
+		prefs.SetBool("trashduplicates", b)
+	})
+	trash.Checked = prefs.Bool("trashduplicates")
+
+	progress := widget.NewProgressBar()
+	progress.Max = float64(len(paths))
+	status := widget.NewLabel("Hashing images")
+	groupsbox := container.NewVBox()
+
+	// the worker that hashes the last image groups them too, so everything here is shared with the ui
+	var groupmu sync.Mutex
+	var groups [][]int
+	keep := make(map[int]bool)
+	hashed := false
+
+	regroup := func() {
+		groupmu.Lock()
+		defer groupmu.Unlock()
+		if !hashed {
+			return
+		}
+		groups = dataset.GroupDuplicates(hashes, dataset.HashKind(kind.SelectedIndex()), int(threshold.Value))
+		clear(keep)
+		groupsbox.RemoveAll()
+		for n, group := range groups {
+			items := container.NewHBox()
+			for i, id := range group {
+				// the first of every group stays unless told otherwise
+				keep[id] = i == 0
+				img := canvas.NewImageFromResource(theme.FileImageIcon())
+				if thumb := p.loadedImage(id).GetImage().Image; thumb != nil {
+					img = canvas.NewImageFromImage(thumb)
+				}
+				img.FillMode = canvas.ImageFillContain
+				img.SetMinSize(fyne.NewSquareSize(160))
+				name := widget.NewLabel(p.RelPath(&p.Entries[id]))
+				name.Truncation = fyne.TextTruncateEllipsis
+				check := widget.NewCheck("Keep", func(b bool) {
+					groupmu.Lock()
+					keep[id] = b
+					groupmu.Unlock()
+				})
+				check.Checked = keep[id]
+				items.Add(container.NewBorder(nil, container.NewVBox(name, check), nil, nil, img))
+			}
+			groupsbox.Add(widget.NewCard("", fmt.Sprintf("Group %d, %d images", n+1, len(group)), container.NewHScroll(items)))
+		}
+		status.SetText(fmt.Sprintf("%d groups", len(groups)))
+	}
+
+	kind.OnChanged = func(string) {
+		prefs.SetInt("duplicatehash", kind.SelectedIndex())
+		regroup()
+	}
+	threshold.OnChanged = func(f float64) {
+		thresholdlabel.SetText(fmt.Sprintf("%0.0f", f))
+	}
+	threshold.OnChangeEnded = func(f float64) {
+		prefs.SetInt("duplicatedistance", int(f))
+		regroup()
+	}
+	kind.SetSelectedIndex(prefs.IntWithFallback("duplicatehash", int(dataset.PHash)))
+	threshold.SetValue(float64(prefs.IntWithFallback("duplicatedistance", 6)))
+
+	removebutton := widget.NewButtonWithIcon("Remove unchecked", theme.DeleteIcon(), nil)
+	removebutton.Importance = widget.DangerImportance
+
+	form := widget.NewForm(
+		widget.NewFormItem("Hash", kind),
+		widget.NewFormItem("Max distance", container.NewBorder(nil, nil, nil, thresholdlabel, threshold)),
+	)
+	content := container.NewBorder(
+		container.NewVBox(form, progress, status),
+		container.NewBorder(nil, nil, nil, removebutton, trash),
+		nil, nil,
+		container.NewVScroll(groupsbox),
+	)
+	d := dialog.NewCustom("Find Duplicates", "Close", content, g.w)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d.SetOnClosed(cancel)
+
+	removebutton.OnTapped = func() {
+		var ids []int
+		groupmu.Lock()
+		for _, group := range groups {
+			for _, id := range group {
+				if !keep[id] {
+					ids = append(ids, id)
+				}
+			}
+		}
+		groupmu.Unlock()
+		if len(ids) < 1 {
+			return
+		}
+
+		msg := fmt.Sprintf("Remove %d images from the project?", len(ids))
+		if trash.Checked {
+			msg += "\nTheir files are moved to " + p.TrashDir()
+		}
+		dialog.ShowConfirm("Remove Duplicates", msg, func(b bool) {
+			if !b {
+				return
+			}
+			var errs []error
+			if trash.Checked {
+				// what could not be moved stays in the project
+				moved := ids[:0]
+				for _, id := range ids {
+					err := p.Trash(&p.Entries[id], p.TrashDir())
+					if err != nil {
+						errs = append(errs, err)
+						continue
+					}
+					moved = append(moved, id)
+				}
+				ids = moved
+			}
+			d.Hide()
+			remove(ids)
+			if err := errors.Join(errs...); err != nil {
+				dialog.ShowError(err, g.w)
+			}
+		}, g.w)
+	}
+
+	var mu sync.Mutex
+	done := 0
+	newthumbnailer(thumbsize).loadall(ctx, paths, func(path string, img image.Image, err error) {
+		var h *dataset.Hashes
+		if err == nil {
+			hash := dataset.HashImage(img)
+			h = &hash
+		}
+
+		mu.Lock()
+		hashes[index[path]] = h
+		done++
+		count := done
+		mu.Unlock()
+
+		progress.SetValue(float64(count))
+		finished := count == len(paths)
+		if finished {
+			progress.Hide()
+			groupmu.Lock()
+			hashed = true
+			groupmu.Unlock()
+			regroup()
+		}
+	})
+
+	d.Show()
+	d.Resize(g.w.Canvas().Size().Subtract(fyne.NewSquareSize(100)))
+}
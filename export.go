@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"biehdc.priv.aidatasetmanager/dataset"
+)
+
+// exportproject writes a resized and cropped copy of the dataset into another folder
+func (g *gui) exportproject(p *projectStructure) {
+	prefs := g.a.Preferences()
+
+	target := widget.NewEntry()
+	target.SetText(filepath.Clean(p.Dir) + "_export")
+	browse := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		d := dialog.NewFolderOpen(func(lu fyne.ListableURI, err error) {
+			if err != nil || lu == nil {
+				return
+			}
+			target.SetText(lu.Path())
+		}, g.w)
+		d.SetLocation(currentPathAsURI(filepath.Dir(filepath.Clean(p.Dir))))
+		d.Show()
+		d.Resize(d.MinSize().Add(d.MinSize()))
+	})
+
+	const square, buckets = "Square", "Nearest bucket"
+	size := widget.NewEntry()
+	size.SetText(strconv.Itoa(prefs.IntWithFallback("exportsize", dataset.DefaultBucketOptions.Resolution)))
+	step := widget.NewEntry()
+	step.SetText(strconv.Itoa(prefs.IntWithFallback("bucketstep", dataset.DefaultBucketOptions.Step)))
+	mode := widget.NewRadioGroup([]string{square, buckets}, func(s string) {
+		if s == buckets {
+			step.Enable()
+		} else {
+			step.Disable()
+		}
+	})
+	mode.Horizontal = true
+	mode.Required = true
+	mode.SetSelected(square)
+	if prefs.Bool("exportbuckets") {
+		mode.SetSelected(buckets)
+	}
+
+	qualitylabel := widget.NewLabel("")
+	quality := widget.NewSlider(1, 100)
+	quality.Step = 1
+	quality.OnChanged = func(f float64) {
+		qualitylabel.SetText(fmt.Sprintf("%0.0f", f))
+	}
+	quality.SetValue(float64(prefs.IntWithFallback("exportquality", 95)))
+	format := widget.NewSelect([]string{dataset.ExportPNG.String(), dataset.ExportJPEG.String()}, nil)
+	format.OnChanged = func(string) {
+		if dataset.ExportFormat(format.SelectedIndex()) == dataset.ExportJPEG {
+			quality.Enable()
+		} else {
+			quality.Disable()
+		}
+	}
+	format.SetSelectedIndex(prefs.IntWithFallback("exportformat", int(dataset.ExportPNG)))
+
+	form := widget.NewForm(
+		widget.NewFormItem("Folder", container.NewBorder(nil, nil, nil, browse, target)),
+		widget.NewFormItem("Resize to", mode),
+		widget.NewFormItem("Resolution", size),
+		widget.NewFormItem("Bucket step", step),
+		widget.NewFormItem("Format", format),
+		widget.NewFormItem("Quality", container.NewBorder(nil, nil, nil, qualitylabel, quality)),
+	)
+	d := dialog.NewCustomConfirm("Export", "Export", "Cancel", form, func(b bool) {
+		if !b {
+			return
+		}
+		res, err1 := strconv.Atoi(size.Text)
+		st, err2 := strconv.Atoi(step.Text)
+		if err1 != nil || err2 != nil || res < 64 || st < 1 || target.Text == "" {
+			dialog.ShowInformation("Export", "The folder, resolution and step need to be filled in", g.w)
+			return
+		}
+		prefs.SetInt("exportsize", res)
+		prefs.SetInt("bucketstep", st)
+		prefs.SetBool("exportbuckets", mode.Selected == buckets)
+		prefs.SetInt("exportformat", format.SelectedIndex())
+		prefs.SetInt("exportquality", int(quality.Value))
+
+		o := dataset.ExportOptions{
+			Size:    res,
+			Buckets: dataset.DefaultBucketOptions,
+			Format:  dataset.ExportFormat(format.SelectedIndex()),
+			Quality: int(quality.Value),
+		}
+		if mode.Selected == buckets {
+			o.Size = 0
+			o.Buckets.Resolution = res
+			o.Buckets.Step = st
+			o.Buckets.MaxSize = max(o.Buckets.MaxSize, res*2)
+		}
+
+		progress := widget.NewProgressBar()
+		progress.Max = float64(len(p.Entries))
+		o.Progress = func(done int) { progress.SetValue(float64(done)) }
+		running := dialog.NewCustomWithoutButtons("Exporting to "+target.Text, progress, g.w)
+		running.Show()
+		running.Resize(fyne.NewSize(400, running.MinSize().Height))
+
+		dir := target.Text
+		go func() {
+			// exporting takes long, so it works on a copy and tags can still be edited meanwhile
+			var project *dataset.Project
+			p.history.Read(func(current *dataset.Project) {
+				project = current.Copy()
+			})
+			summary, err := dataset.Export(project, dir, o)
+			running.Hide()
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("%s\n%w", summary, err), g.w)
+				return
+			}
+			dialog.ShowInformation("Export", summary.String(), g.w)
+		}()
+	}, g.w)
+	d.Show()
+	d.Resize(fyne.NewSize(600, d.MinSize().Height))
+}
@@ -2,7 +2,10 @@ module biehdc.priv.aidatasetmanager
 
 go 1.23.2
 
-require fyne.io/fyne/v2 v2.5.2
+require (
+	fyne.io/fyne/v2 v2.5.2
+	golang.org/x/image v0.22.0
+)
 
 // remove after CheckGroup.SetColumns has been merged
 replace fyne.io/fyne/v2 => github.com/BieHDC/fyne/v2 v2.0.0-20241102203948-d178c85b8dbe
@@ -31,7 +34,6 @@ require (
 	github.com/srwiley/rasterx v0.0.0-20220730225603-2ab79fcdd4ef // indirect
 	github.com/stretchr/testify v1.9.0 // indirect
 	github.com/yuin/goldmark v1.7.8 // indirect
-	golang.org/x/image v0.22.0 // indirect
 	golang.org/x/mobile v0.0.0-20241108191957-fa514ef75a0f // indirect
 	golang.org/x/net v0.31.0 // indirect
 	golang.org/x/sys v0.27.0 // indirect
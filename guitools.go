@@ -1,7 +1,6 @@
 package main
 
 import (
-	"os"
 	"path/filepath"
 
 	"fyne.io/fyne/v2"
@@ -11,14 +10,14 @@ import (
 )
 
 // guitools
-func (g *gui) openfolder(title string, location *fyne.ListableURI, cb func(fyne.ListableURI, []fyne.URI)) *widget.Button {
+func (g *gui) openfolder(title string, location *fyne.ListableURI, cb func(fyne.ListableURI)) *widget.Button {
 	return widget.NewButton(title, func() {
 		d := dialog.NewFolderOpen(func(lu fyne.ListableURI, err error) {
 			if err != nil || lu == nil {
 				return
 			}
 
-			cb(lu, filesinfolder(lu))
+			cb(lu)
 		}, g.w)
 
 		if location != nil && *location != nil {
@@ -32,23 +31,6 @@ func (g *gui) openfolder(title string, location *fyne.ListableURI, cb func(fyne.
 	})
 }
 
-func filesinfolder(lu fyne.ListableURI) []fyne.URI {
-	items, _ := lu.List()
-	files := make([]fyne.URI, 0, len(items))
-	for _, uri := range items {
-		fileinfo, err := os.Lstat(uri.Path())
-		if err != nil {
-			continue
-		}
-		mode := fileinfo.Mode()
-
-		if mode.IsRegular() {
-			files = append(files, uri)
-		}
-	}
-	return files
-}
-
 // dont forget to defer uc.Close()
 func (g *gui) openfile(title string, location *fyne.ListableURI, cb func(uc fyne.URIReadCloser) bool) *widget.Button {
 	var buttonopen *widget.Button
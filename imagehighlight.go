@@ -1,6 +1,7 @@
 package main
 
 import (
+	"image"
 	"image/color"
 
 	"fyne.io/fyne/v2"
@@ -12,22 +13,43 @@ import (
 type ImageHighlightable struct {
 	widget.BaseWidget
 	image *canvas.Image
-	rect  canvas.Rectangle
-	col   color.NRGBA
+	// the mask drawn over the image, hidden when there is none
+	overlay *canvas.Image
+	rect    canvas.Rectangle
+	col     color.NRGBA
+	// outlines the crop, hidden when there is none
+	crop canvas.Rectangle
+	// the crop as parts of the image size, so it fits whatever size is shown
+	cropfrom, cropto fyne.Position
+	// aspect ratio of the original image
+	aspect float32
+
+	OnDoubleTapped func()
 }
 
 var _ fyne.Widget = (*ImageHighlightable)(nil)
+var _ fyne.DoubleTappable = (*ImageHighlightable)(nil)
 
 func NewImageHighlightable(image *canvas.Image) *ImageHighlightable {
 	cc := color.NRGBA{R: 255, G: 0, B: 0, A: 0}
+	overlay := canvas.NewImageFromImage(nil)
+	overlay.FillMode = image.FillMode
+	overlay.ScaleMode = image.ScaleMode
+	overlay.Hide()
 	ih := &ImageHighlightable{
-		image: image,
+		image:   image,
+		overlay: overlay,
 		rect: canvas.Rectangle{
 			StrokeColor: cc,
 			StrokeWidth: theme.Padding() / 2,
 		},
 		col: cc,
+		crop: canvas.Rectangle{
+			StrokeColor: cropcolor,
+			StrokeWidth: theme.Padding() / 2,
+		},
 	}
+	ih.crop.Hide()
 	ih.ExtendBaseWidget(ih)
 	return ih
 }
@@ -41,11 +63,57 @@ func (ih *ImageHighlightable) SetHighlight(highlight bool) {
 	ih.rect.StrokeColor = ih.col
 }
 
+func (ih *ImageHighlightable) DoubleTapped(*fyne.PointEvent) {
+	if ih.OnDoubleTapped != nil {
+		ih.OnDoubleTapped()
+	}
+}
+
 // MinSize returns the size that this widget should not shrink below
 func (ih *ImageHighlightable) MinSize() fyne.Size {
 	return ih.image.MinSize().AddWidthHeight(theme.Padding(), theme.Padding())
 }
 
+// SetImage replaces what is shown, the thumbnail workers call this
+func (ih *ImageHighlightable) SetImage(img image.Image) {
+	ih.image.Resource = nil
+	ih.image.Image = img
+	ih.image.Refresh()
+}
+
+// SetResource is like SetImage but for icons
+func (ih *ImageHighlightable) SetResource(res fyne.Resource) {
+	ih.image.Image = nil
+	ih.image.Resource = res
+	ih.image.Refresh()
+}
+
+// SetMask shows the mask as a translucent overlay, nil removes it
+func (ih *ImageHighlightable) SetMask(mask image.Image) {
+	if mask == nil {
+		ih.overlay.Image = nil
+		ih.overlay.Hide()
+		return
+	}
+	ih.overlay.Image = maskoverlay(mask)
+	ih.overlay.Show()
+	ih.overlay.Refresh()
+}
+
+// SetCrop outlines the crop of an image that is width by height pixels big, nil removes it
+func (ih *ImageHighlightable) SetCrop(crop *image.Rectangle, width, height int) {
+	if crop == nil || width < 1 || height < 1 {
+		ih.crop.Hide()
+		return
+	}
+	w, h := float32(width), float32(height)
+	ih.aspect = w / h
+	ih.cropfrom = fyne.NewPos(float32(crop.Min.X)/w, float32(crop.Min.Y)/h)
+	ih.cropto = fyne.NewPos(float32(crop.Max.X)/w, float32(crop.Max.Y)/h)
+	ih.crop.Show()
+	ih.Refresh()
+}
+
 func (ih *ImageHighlightable) GetImage() *canvas.Image {
 	return ih.image
 }
@@ -66,7 +134,7 @@ func (ih *ImageHighlightable) CreateRenderer() fyne.WidgetRenderer {
 func (c *imageHighlightableRenderer) Destroy() {}
 
 func (c *imageHighlightableRenderer) Objects() []fyne.CanvasObject {
-	return []fyne.CanvasObject{c.ih.image, &c.ih.rect}
+	return []fyne.CanvasObject{c.ih.image, c.ih.overlay, &c.ih.crop, &c.ih.rect}
 }
 
 // Layout the components of the card container.
@@ -75,9 +143,24 @@ func (c *imageHighlightableRenderer) Layout(size fyne.Size) {
 		c.ih.image.Move(fyne.NewPos(c.ih.rect.StrokeWidth, c.ih.rect.StrokeWidth))
 		c.ih.image.Resize(size.Subtract(fyne.NewSquareSize(c.ih.rect.StrokeWidth * 2)))
 	}
+	c.ih.overlay.Move(fyne.NewPos(c.ih.rect.StrokeWidth, c.ih.rect.StrokeWidth))
+	c.ih.overlay.Resize(size.Subtract(fyne.NewSquareSize(c.ih.rect.StrokeWidth * 2)))
 
 	c.ih.rect.Move(fyne.NewPos(0, 0))
 	c.ih.rect.Resize(size)
+
+	if c.ih.crop.Visible() {
+		// the image is letterboxed inside of the border
+		inner := size.Subtract(fyne.NewSquareSize(c.ih.rect.StrokeWidth * 2))
+		shown := fyne.NewSize(inner.Width, inner.Width/c.ih.aspect)
+		if shown.Height > inner.Height {
+			shown = fyne.NewSize(inner.Height*c.ih.aspect, inner.Height)
+		}
+		origin := fyne.NewPos(c.ih.rect.StrokeWidth+(inner.Width-shown.Width)/2, c.ih.rect.StrokeWidth+(inner.Height-shown.Height)/2)
+		from, to := c.ih.cropfrom, c.ih.cropto
+		c.ih.crop.Move(origin.AddXY(from.X*shown.Width, from.Y*shown.Height))
+		c.ih.crop.Resize(fyne.NewSize((to.X-from.X)*shown.Width, (to.Y-from.Y)*shown.Height))
+	}
 }
 
 // MinSize calculates the minimum size of a card.
@@ -92,4 +175,5 @@ func (c *imageHighlightableRenderer) Refresh() {
 		c.ih.image.Refresh()
 	}
 	c.ih.rect.Refresh()
+	c.ih.crop.Refresh()
 }
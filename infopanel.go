@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+
+	"biehdc.priv.aidatasetmanager/dataset"
+)
+
+// entryinfo lists what is known about an entry besides its tags,
+// the extra jsonl fields are only shown, they are saved as they were loaded
+func entryinfo(p *dataset.Project, e *dataset.Entry) *widget.Form {
+	form := widget.NewForm()
+	add := func(name, value string) {
+		label := widget.NewLabel(value)
+		label.Wrapping = fyne.TextWrapBreak
+		form.Append(name, label)
+	}
+
+	add("File", p.RelPath(e))
+	if e.Mask != nil {
+		add("Mask", *e.Mask)
+	}
+	if e.Crop != nil {
+		add("Crop", fmt.Sprintf("%dx%d at %d,%d", e.Crop.Dx(), e.Crop.Dy(), e.Crop.Min.X, e.Crop.Min.Y))
+	}
+	for _, key := range slices.Sorted(maps.Keys(e.Extra)) {
+		add(key, extravalue(e.Extra[key]))
+	}
+	return form
+}
+
+// extravalue shows strings without their quotes and everything else as json
+func extravalue(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	return string(raw)
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"biehdc.priv.aidatasetmanager/dataset"
+)
+
+func (g *gui) lintoptions() dataset.LintOptions {
+	prefs := g.a.Preferences()
+	o := dataset.DefaultLintOptions
+	o.MinSize = prefs.IntWithFallback("lintminsize", o.MinSize)
+	o.MinSharpness = prefs.FloatWithFallback("lintminsharpness", o.MinSharpness)
+	o.MaxBlockiness = prefs.FloatWithFallback("lintmaxblockiness", o.MaxBlockiness)
+	o.MinFileSize = int64(prefs.IntWithFallback("lintminfilesize", int(o.MinFileSize>>10))) << 10
+	return o
+}
+
+// lintrow is one line of the report
+type lintrow struct {
+	id     int
+	path   string
+	result *dataset.LintResult
+}
+
+// lintproject checks every image for things that hurt training and lists them.
+// last are the results of the previous check, if any.
+// linted is called with the results by path once all images are done.
+func (g *gui) lintproject(p *projectStructure, last map[string]*dataset.LintResult, linted func(results map[string]*dataset.LintResult)) {
+	prefs := g.a.Preferences()
+	o := g.lintoptions()
+
+	minsize := widget.NewEntry()
+	minsize.SetText(strconv.Itoa(o.MinSize))
+	minsharpness := widget.NewEntry()
+	minsharpness.SetText(strconv.FormatFloat(o.MinSharpness, 'f', -1, 64))
+	maxblockiness := widget.NewEntry()
+	maxblockiness.SetText(strconv.FormatFloat(o.MaxBlockiness, 'f', -1, 64))
+	minfilesize := widget.NewEntry()
+	minfilesize.SetText(strconv.FormatInt(o.MinFileSize>>10, 10))
+
+	onlyissues := widget.NewCheck("Only images with issues", nil)
+	onlyissues.Checked = true
+	progress := widget.NewProgressBar()
+	progress.Hide()
+	status := widget.NewLabel("")
+
+	// the check fills rows from its own goroutine, the sorting is read there too
+	var rowsmu sync.Mutex
+	var rows, shown []lintrow
+	sortcol, ascending := 5, false
+	columns := []string{"File", "Size", "Sharpness", "Blockiness", "File size", "Issues"}
+	compare := func(a, b lintrow) int {
+		ra, rb := a.result, b.result
+		var c int
+		switch sortcol {
+		case 0:
+			c = cmp.Compare(a.path, b.path)
+		case 1:
+			c = cmp.Compare(min(ra.Width, ra.Height), min(rb.Width, rb.Height))
+		case 2:
+			c = cmp.Compare(ra.Sharpness, rb.Sharpness)
+		case 3:
+			c = cmp.Compare(ra.Blockiness, rb.Blockiness)
+		case 4:
+			c = cmp.Compare(ra.FileSize, rb.FileSize)
+		default:
+			c = cmp.Compare(len(ra.Issues), len(rb.Issues))
+		}
+		if !ascending {
+			c = -c
+		}
+		return cmp.Or(c, cmp.Compare(a.id, b.id))
+	}
+
+	var table *widget.Table
+	reshow := func() {
+		rowsmu.Lock()
+		shown = shown[:0]
+		issues := 0
+		for _, row := range rows {
+			if len(row.result.Issues) > 0 {
+				issues++
+			} else if onlyissues.Checked {
+				continue
+			}
+			shown = append(shown, row)
+		}
+		slices.SortFunc(shown, compare)
+		total := len(rows)
+		rowsmu.Unlock()
+		status.SetText(fmt.Sprintf("%d of %d images with issues", issues, total))
+		table.Refresh()
+	}
+	onlyissues.OnChanged = func(bool) { reshow() }
+
+	table = widget.NewTableWithHeaders(
+		func() (int, int) {
+			rowsmu.Lock()
+			defer rowsmu.Unlock()
+			return len(shown), len(columns)
+		},
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("averagefilename.len")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(tci widget.TableCellID, co fyne.CanvasObject) {
+			rowsmu.Lock()
+			if tci.Row >= len(shown) {
+				rowsmu.Unlock()
+				return
+			}
+			row := shown[tci.Row]
+			rowsmu.Unlock()
+			r := row.result
+			var text string
+			switch tci.Col {
+			case 0:
+				text = row.path
+			case 1:
+				text = fmt.Sprintf("%dx%d", r.Width, r.Height)
+			case 2:
+				text = fmt.Sprintf("%.0f", r.Sharpness)
+			case 3:
+				if r.Blockiness > 0 {
+					text = fmt.Sprintf("%.2f", r.Blockiness)
+				}
+			case 4:
+				text = fmt.Sprintf("%d KiB", r.FileSize>>10)
+			default:
+				text = r.String()
+			}
+			co.(*widget.Label).SetText(text)
+		},
+	)
+	table.ShowHeaderColumn = false
+	table.CreateHeader = func() fyne.CanvasObject {
+		return widget.NewButton("", nil)
+	}
+	table.UpdateHeader = func(tci widget.TableCellID, co fyne.CanvasObject) {
+		if tci.Col < 0 {
+			return
+		}
+		button := co.(*widget.Button)
+		button.SetText(columns[tci.Col])
+		button.SetIcon(nil)
+		if tci.Col == sortcol {
+			button.SetIcon(theme.MenuDropDownIcon())
+			if ascending {
+				button.SetIcon(theme.MenuDropUpIcon())
+			}
+		}
+		button.OnTapped = func() {
+			rowsmu.Lock()
+			if sortcol == tci.Col {
+				ascending = !ascending
+			} else {
+				sortcol, ascending = tci.Col, tci.Col <= 2
+			}
+			rowsmu.Unlock()
+			reshow()
+		}
+	}
+	table.OnSelected = func(tci widget.TableCellID) {
+		table.UnselectAll()
+		rowsmu.Lock()
+		var path string
+		if tci.Row < len(shown) {
+			path = p.Entries[shown[tci.Row].id].ImagePath
+		}
+		rowsmu.Unlock()
+		if path != "" {
+			g.showoriginal(path)
+		}
+	}
+	table.SetColumnWidth(0, 400)
+	for i := 1; i < len(columns)-1; i++ {
+		table.SetColumnWidth(i, 120)
+	}
+	table.SetColumnWidth(len(columns)-1, 300)
+
+	// the last check is still worth looking at
+	p.history.Read(func(project *dataset.Project) {
+		for i := range project.Entries {
+			if r, ok := last[project.Entries[i].ImagePath]; ok {
+				rows = append(rows, lintrow{id: i, path: p.RelPath(&project.Entries[i]), result: r})
+			}
+		}
+	})
+	if len(rows) > 0 {
+		reshow()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	run := widget.NewButtonWithIcon("Check", theme.SearchIcon(), nil)
+	run.Importance = widget.HighImportance
+	run.OnTapped = func() {
+		size, err1 := strconv.Atoi(minsize.Text)
+		sharpness, err2 := strconv.ParseFloat(minsharpness.Text, 64)
+		blockiness, err3 := strconv.ParseFloat(maxblockiness.Text, 64)
+		kib, err4 := strconv.ParseInt(minfilesize.Text, 10, 64)
+		if errors.Join(err1, err2, err3, err4) != nil || size < 0 || sharpness < 0 || blockiness < 0 || kib < 0 {
+			status.SetText("The limits have to be numbers and not negative")
+			return
+		}
+		o.MinSize = size
+		o.MinSharpness = sharpness
+		o.MaxBlockiness = blockiness
+		o.MinFileSize = kib << 10
+		prefs.SetInt("lintminsize", o.MinSize)
+		prefs.SetFloat("lintminsharpness", o.MinSharpness)
+		prefs.SetFloat("lintmaxblockiness", o.MaxBlockiness)
+		prefs.SetInt("lintminfilesize", int(kib))
+
+		run.Disable()
+		rowsmu.Lock()
+		rows = nil
+		rowsmu.Unlock()
+		reshow()
+		paths := make([]string, len(p.Entries))
+		for i, e := range p.Entries {
+			paths[i] = e.ImagePath
+		}
+		progress.Max = float64(len(paths))
+		progress.SetValue(0)
+		progress.Show()
+
+		// a full decode each, so this does not go through the thumbnail cache
+		go func() {
+			results := make([]*dataset.LintResult, len(paths))
+			var done sync.WaitGroup
+			var mu sync.Mutex
+			count := 0
+			jobs := make(chan int)
+			for range max(runtime.NumCPU()-1, 1) {
+				done.Add(1)
+				go func() {
+					defer done.Done()
+					for i := range jobs {
+						r, err := dataset.LintImage(paths[i], o)
+						mu.Lock()
+						if err == nil {
+							results[i] = &r
+						}
+						count++
+						progress.SetValue(float64(count))
+						mu.Unlock()
+					}
+				}()
+			}
+		send:
+			for i := range paths {
+				select {
+				case jobs <- i:
+				case <-ctx.Done():
+					break send
+				}
+			}
+			close(jobs)
+			done.Wait()
+			if ctx.Err() != nil {
+				return
+			}
+
+			bypath := make(map[string]*dataset.LintResult, len(paths))
+			for i, r := range results {
+				if r == nil {
+					continue // the thumbnails already report what does not decode
+				}
+				bypath[paths[i]] = r
+			}
+			var checked []lintrow
+			p.history.Read(func(project *dataset.Project) {
+				for i := range project.Entries {
+					e := &project.Entries[i]
+					if r, ok := bypath[e.ImagePath]; ok {
+						e.Width, e.Height = r.Width, r.Height
+						checked = append(checked, lintrow{id: i, path: p.RelPath(e), result: r})
+					}
+				}
+			})
+			rowsmu.Lock()
+			rows = checked
+			rowsmu.Unlock()
+			progress.Hide()
+			run.Enable()
+			reshow()
+			linted(bypath)
+		}()
+	}
+
+	form := widget.NewForm(
+		widget.NewFormItem("Min size", minsize),
+		widget.NewFormItem("Min sharpness", minsharpness),
+		widget.NewFormItem("Max blockiness", maxblockiness),
+		widget.NewFormItem("Min file size (KiB)", minfilesize),
+	)
+	content := container.NewBorder(
+		container.NewVBox(form, container.NewBorder(nil, nil, onlyissues, run, status), progress),
+		nil, nil, nil,
+		table,
+	)
+	d := dialog.NewCustom("Image Quality", "Close", content, g.w)
+	d.SetOnClosed(cancel)
+	d.Show()
+	d.Resize(g.w.Canvas().Size().Subtract(fyne.NewSquareSize(100)))
+}
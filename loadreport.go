@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"biehdc.priv.aidatasetmanager/dataset"
+)
+
+// showproblems lists everything that went wrong while loading in one place
+func (g *gui) showproblems(problems []dataset.Problem) {
+	if len(problems) < 1 {
+		return
+	}
+
+	const allcategories = "All"
+	categories := []string{allcategories}
+	counts := make(map[string]int)
+	for _, problem := range problems {
+		counts[problem.Category]++
+	}
+	categories = append(categories, dataset.ProblemCategories(problems)...)
+	// the select shows the counts, this maps back to the category
+	labels := make(map[string]string)
+	for i, category := range categories {
+		count := len(problems)
+		if category != allcategories {
+			count = counts[category]
+		}
+		label := fmt.Sprintf("%s (%d)", category, count)
+		labels[label] = category
+		categories[i] = label
+	}
+
+	shown := problems
+	columns := []string{"Category", "File", "Reason"}
+	table := widget.NewTableWithHeaders(
+		func() (int, int) { return len(shown), len(columns) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("averagecategory.len")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(tci widget.TableCellID, co fyne.CanvasObject) {
+			problem := shown[tci.Row]
+			text := problem.Category
+			switch tci.Col {
+			case 1:
+				text = problem.Path
+			case 2:
+				text = problem.Reason
+			}
+			co.(*widget.Label).SetText(text)
+		},
+	)
+	table.ShowHeaderColumn = false
+	table.CreateHeader = func() fyne.CanvasObject {
+		return widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	}
+	table.UpdateHeader = func(tci widget.TableCellID, co fyne.CanvasObject) {
+		if tci.Col >= 0 {
+			co.(*widget.Label).SetText(columns[tci.Col])
+		}
+	}
+	table.SetColumnWidth(0, 200)
+	table.SetColumnWidth(1, 500)
+	table.SetColumnWidth(2, 300)
+
+	status := widget.NewLabel("")
+	category := widget.NewSelect(categories, nil)
+	search := widget.NewEntry()
+	search.SetPlaceHolder("Search files and reasons")
+	refilter := func() {
+		c := labels[category.Selected]
+		if c == allcategories {
+			c = ""
+		}
+		shown = dataset.FilterProblems(problems, c, search.Text)
+		status.SetText(fmt.Sprintf("%d of %d", len(shown), len(problems)))
+		table.Refresh()
+		table.ScrollToTop()
+	}
+	category.OnChanged = func(string) { refilter() }
+	search.OnChanged = func(string) { refilter() }
+	category.SetSelectedIndex(0)
+
+	// exports what is shown so a filtered list can be handed on
+	export := widget.NewButtonWithIcon("Export", theme.DocumentSaveIcon(), func() {
+		exported := shown
+		d := dialog.NewFileSave(func(uc fyne.URIWriteCloser, err error) {
+			if err != nil || uc == nil {
+				return
+			}
+			err = dataset.WriteProblemsCSV(uc, exported)
+			if cerr := uc.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				dialog.ShowError(err, g.w)
+			}
+		}, g.w)
+		d.SetFileName("loadreport.csv")
+		d.Show()
+		d.Resize(d.MinSize().Add(d.MinSize()))
+	})
+
+	content := container.NewBorder(
+		container.NewBorder(nil, nil, category, container.NewHBox(status, export), search),
+		nil, nil, nil,
+		table,
+	)
+	d := dialog.NewCustom(fmt.Sprintf("%d files could not be used", len(problems)), "Close", content, g.w)
+	d.Show()
+	d.Resize(g.w.Canvas().Size().Subtract(fyne.NewSquareSize(100)))
+}
@@ -1,172 +1,73 @@
 package main
 
 import (
-	"bufio"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"slices"
+	"os"
+	"path/filepath"
 	"strings"
 
-	"image"
+	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
 
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+
+	"biehdc.priv.aidatasetmanager/dataset"
 )
 
+const apptitle = "Ai Dataset Manager"
+
 type gui struct {
 	a fyne.App
 	w fyne.Window
 }
 
 func main() {
+	if len(os.Args) > 1 && iscommand(os.Args[1]) {
+		os.Exit(runcli(os.Args[1:]))
+	}
+
 	g := gui{}
 
 	g.a = app.NewWithID("biehdc.priv.aidatasetmanager")
-	g.w = g.a.NewWindow("Ai Dataset Manager")
+	g.w = g.a.NewWindow(apptitle)
 	g.w.CenterOnScreen()
 	g.w.Resize(fyne.NewSize(1128, 768))
 
 	g.w.SetContent(g.content())
+	// like "open with" from a file manager
+	if len(os.Args) > 1 {
+		path, err := filepath.Abs(os.Args[1])
+		if err != nil {
+			dialog.ShowError(err, g.w)
+		} else if g.openuri(storage.NewFileURI(path)) {
+			g.w.SetOnDropped(nil)
+		}
+	}
 	g.w.ShowAndRun()
 }
 
 func (g *gui) content() fyne.CanvasObject {
-	dirhandler := func(lu fyne.ListableURI, u []fyne.URI) {
-		entries := make(map[string]imageEntry)
-
-		for _, fileuri := range u {
-			// filter filenames
-			extension := fileuri.Extension()
-			switch extension {
-			case ".txt", ".png", ".jpg", ".jpeg":
-				// we gaming
-			default:
-				dialog.ShowError(fmt.Errorf("unhandled file extension: %s", extension), g.w)
-				continue
-			}
-
-			key := strings.TrimRight(fileuri.Path(), extension)
-			knowndata := entries[key]
-
-			content, err := storage.Reader(fileuri)
-			if err != nil {
-				dialog.ShowError(fmt.Errorf("failed to read file: %w", err), g.w)
-				continue
-			}
-
-			if extension == ".txt" {
-				knowndata.Tags = loadtags(content)
-
-			} else {
-				nih, err := loadimage(content)
-				if err != nil {
-					dialog.ShowError(fmt.Errorf("failed to decode image: %w", err), g.w)
-					goto itsthisorgettingannoyedbydeferinsideloop
-				}
-				knowndata.loadedImage = nih
-				knowndata.ImagePath = fileuri
-			}
-
-			entries[key] = knowndata
-
-		itsthisorgettingannoyedbydeferinsideloop: // cope and seethe about it
-			content.Close()
-		}
-
-		project := projectStructure{parentdir: lu}
-		for k, v := range entries {
-			if v.ImagePath == nil {
-				dialog.ShowError(fmt.Errorf("file %s.txt has no assosiacted image path, skipping", k), g.w)
-				continue
-			}
-			if v.loadedImage == nil {
-				dialog.ShowError(fmt.Errorf("file %s.txt has no loaded image, skipping", k), g.w)
-				continue
-			}
-			project.data = append(project.data, v)
-		}
-		if len(project.data) < 1 {
-			dialog.ShowError(fmt.Errorf("there was nothing useable in this folder"), g.w)
-			return
-		}
-		slices.SortFunc(project.data, func(a, b imageEntry) int {
-			return strings.Compare(a.ImagePath.Path(), b.ImagePath.Path())
-		})
-
-		g.w.SetContent(g.projectview(project))
-	}
-	asdir := g.openfolder("Open Folder With Images", nil, dirhandler)
-
-	jsonlhandler := func(uc fyne.URIReadCloser) bool {
-		defer uc.Close()
-		parent2, err := storage.Parent(uc.URI())
-		if err != nil {
-			dialog.ShowError(fmt.Errorf("could not get parent for the jsonl: %w", err), g.w)
-			return false
-		}
-		parent, err := storage.ListerForURI(parent2)
-		if err != nil {
-			dialog.ShowError(fmt.Errorf("could not get lister for the jsonl parent: %w", err), g.w)
-			return false
-		}
-
-		project := projectStructure{parentdir: parent}
-
-		entries := bufio.NewScanner(uc)
-		for entries.Scan() {
-			var jsonlline jsonlentry
-			err := json.Unmarshal(entries.Bytes(), &jsonlline)
-			if err != nil {
-				dialog.ShowError(fmt.Errorf("error unmarshalling line: %w", err), g.w)
-				return false
-			}
-
-			ie := imageEntry{
-				ImagePath: storage.NewFileURI(jsonlline.Image),
-				Tags:      loadtags(strings.NewReader(jsonlline.Text)),
-				mask:      jsonlline.Mask,
-			}
-
-			content, err := storage.Reader(ie.ImagePath)
-			if err != nil {
-				dialog.ShowError(fmt.Errorf("failed to read file: %w", err), g.w)
-				continue
-			}
-
-			nih, err := loadimage(content)
-			if err != nil {
-				dialog.ShowError(fmt.Errorf("failed to decode image: %w", err), g.w)
-				goto itsthisorgettingannoyedbydeferinsideloop2
-			}
-			ie.loadedImage = nih
-
-			project.data = append(project.data, ie)
-
-		itsthisorgettingannoyedbydeferinsideloop2: // cope and seethe about it yet again
-			content.Close()
-		}
-
-		if len(project.data) < 1 {
-			dialog.ShowError(fmt.Errorf("there was nothing useable in this jsonl"), g.w)
-			return false
-		}
-		slices.SortFunc(project.data, func(a, b imageEntry) int {
-			return strings.Compare(a.ImagePath.Path(), b.ImagePath.Path())
-		})
+	recursive := widget.NewCheck("Include subfolders", func(b bool) {
+		g.a.Preferences().SetBool("recursive", b)
+	})
+	recursive.Checked = g.a.Preferences().Bool("recursive")
 
-		g.w.SetContent(g.projectview(project))
-		return true
-	}
-	asjsonl := g.openfile("Open JSONL", nil, jsonlhandler)
+	asdir := g.openfolder("Open Folder With Images", nil, g.dirhandler)
+	asjsonl := g.openfile("Open JSONL", nil, g.filehandler(dataset.LoadJSONL))
+	asmetadata := g.openfile("Open "+dataset.MetadataName, nil, g.filehandler(dataset.LoadMetadata))
 
 	g.w.SetOnDropped(func(_ fyne.Position, u []fyne.URI) {
 		if len(u) != 1 {
@@ -174,86 +75,134 @@ func (g *gui) content() fyne.CanvasObject {
 			return
 		}
 
-		uri := u[0]
-		if uri.Extension() == ".jsonl" {
-			rr, err := storage.Reader(uri)
-			if err != nil {
-				dialog.ShowError(fmt.Errorf("failed to read file: %w", err), g.w)
-				return
-			}
-
-			jsonlhandler(rr)
+		if g.openuri(u[0]) {
 			g.w.SetOnDropped(nil) // disable
-
-			return // success
-		}
-
-		cl, err := storage.CanList(uri)
-		if err != nil {
-			dialog.ShowError(fmt.Errorf("cant check if uri is listable: %w", err), g.w)
-			return
 		}
-		if !cl {
-			dialog.ShowError(errors.New("dropped item is nether a jsonl nor a valid directory"), g.w)
-			return
-		}
-		lu, err := storage.ListerForURI(uri)
-		if err != nil {
-			dialog.ShowError(fmt.Errorf("cant enumerate directory: %w", err), g.w)
-			return
-		}
-
-		dirhandler(lu, filesinfolder(lu))
-		g.w.SetOnDropped(nil) // disable
 	})
 
+	extensions := widget.NewButtonWithIcon("File Types", theme.SettingsIcon(), g.editextensions)
+
 	return container.NewCenter(
 		container.NewGridWithColumns(1,
-			container.NewGridWithColumns(2, asjsonl, asdir),
+			container.NewGridWithColumns(2, container.NewVBox(asjsonl, asmetadata), container.NewVBox(asdir, recursive)),
 			container.NewCenter(widget.NewLabel("or drag and drop the item here")),
+			container.NewCenter(extensions),
 		),
 	)
 }
 
-func loadtags(r io.Reader) []string {
-	s := bufio.NewScanner(r)
-	var tags []string
-	for s.Scan() {
-		for _, tag := range strings.Split(s.Text(), ",") {
-			trimmed := strings.TrimSpace(tag)
-			if trimmed == "" {
-				continue
-			}
-			tags = append(tags, trimmed)
+func (g *gui) dirhandler(lu fyne.ListableURI) {
+	project, problems, err := dataset.LoadDir(lu.Path(), g.loadoptions())
+	if err != nil {
+		g.showproblems(dataset.NewProblems(problems))
+		dialog.ShowError(err, g.w)
+		return
+	}
+
+	g.openproject(project, problems)
+}
+
+func (g *gui) filehandler(load func(path string) (*dataset.Project, []error, error)) func(uc fyne.URIReadCloser) bool {
+	return func(uc fyne.URIReadCloser) bool {
+		defer uc.Close()
+
+		project, problems, err := load(uc.URI().Path())
+		if err != nil {
+			g.showproblems(dataset.NewProblems(problems))
+			dialog.ShowError(err, g.w)
+			return false
 		}
+
+		return g.openproject(project, problems)
 	}
+}
 
-	final := make([]string, 0, len(tags))
-	// remove dupes
-	foundtags := make(map[string]struct{})
-	for _, tag := range tags {
-		_, exists := foundtags[tag]
-		if !exists {
-			foundtags[tag] = struct{}{}
-			final = append(final, tag)
+// openuri opens a dropped or passed jsonl file or folder, false if it was neither
+func (g *gui) openuri(uri fyne.URI) bool {
+	if uri.Extension() == ".jsonl" {
+		rr, err := storage.Reader(uri)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("failed to read file: %w", err), g.w)
+			return false
+		}
+
+		if uri.Name() == dataset.MetadataName {
+			g.filehandler(dataset.LoadMetadata)(rr)
+		} else {
+			g.filehandler(dataset.LoadJSONL)(rr)
 		}
+		return true
+	}
+
+	cl, err := storage.CanList(uri)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("cant check if uri is listable: %w", err), g.w)
+		return false
+	}
+	if !cl {
+		dialog.ShowError(errors.New("dropped item is nether a jsonl nor a valid directory"), g.w)
+		return false
+	}
+	lu, err := storage.ListerForURI(uri)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("cant enumerate directory: %w", err), g.w)
+		return false
 	}
 
-	return final
+	g.dirhandler(lu)
+	return true
 }
 
-func loadimage(r io.Reader) (*ImageHighlightable, error) {
-	goimg, _, err := image.Decode(r)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode image: %w", err)
+// loadoptions reads how folders are loaded from the preferences
+func (g *gui) loadoptions() dataset.LoadOptions {
+	prefs := g.a.Preferences()
+	return dataset.LoadOptions{
+		Recursive:         prefs.Bool("recursive"),
+		ImageExtensions:   dataset.ParseExtensions(prefs.String("imageextensions")),
+		CaptionExtensions: dataset.ParseExtensions(prefs.String("captionextensions")),
 	}
+}
+
+// editextensions lets the user pick which files count as images and captions
+func (g *gui) editextensions() {
+	prefs := g.a.Preferences()
+	images := widget.NewEntry()
+	images.SetPlaceHolder(strings.Join(dataset.DefaultImageExtensions, " "))
+	images.SetText(prefs.String("imageextensions"))
+	captions := widget.NewEntry()
+	captions.SetPlaceHolder(strings.Join(dataset.DefaultCaptionExtensions, " "))
+	captions.SetText(prefs.String("captionextensions"))
+
+	d := dialog.NewForm("File Types", "Ok", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Images", images),
+		widget.NewFormItem("Captions", captions),
+		widget.NewFormItem("", widget.NewLabel("Leave empty for the defaults, the first caption type is used for saving")),
+	}, func(b bool) {
+		if !b {
+			return
+		}
+		prefs.SetString("imageextensions", strings.Join(dataset.ParseExtensions(images.Text), " "))
+		prefs.SetString("captionextensions", strings.Join(dataset.ParseExtensions(captions.Text), " "))
+	}, g.w)
+	d.Show()
+	d.Resize(d.MinSize().AddWidthHeight(d.MinSize().Width, 0))
+}
+
+// openproject switches to the project view, the images are loaded in the background.
+// what could not be loaded is shown in one report.
+func (g *gui) openproject(project *dataset.Project, problems []error) bool {
+	g.w.SetContent(g.projectview(projectStructure{Project: project, problems: dataset.NewProblems(problems)}))
+	g.showproblems(dataset.NewProblems(problems))
+	return true
+}
 
-	img := canvas.NewImageFromImage(goimg)
-	neww, newh := calculateNewResolution(goimg.Bounds().Dx(), goimg.Bounds().Dy(), 256)
-	img.SetMinSize(fyne.Size{Width: float32(neww), Height: float32(newh)})
+// newthumbnailimage shows a placeholder until SetImage is called with the thumbnail
+func newthumbnailimage(size float32) *ImageHighlightable {
+	img := canvas.NewImageFromResource(theme.FileImageIcon())
+	img.SetMinSize(fyne.NewSquareSize(size))
 	img.FillMode = canvas.ImageFillContain
 	img.ScaleMode = canvas.ImageScaleSmooth
-	return NewImageHighlightable(img), nil
+	return NewImageHighlightable(img)
 }
 
 func calculateNewResolution[RR LooksLikeNumber](width, height, maxside RR) (RR, RR) {
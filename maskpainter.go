@@ -0,0 +1,316 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	xdraw "golang.org/x/image/draw"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"biehdc.priv.aidatasetmanager/dataset"
+)
+
+// what the mask covers is drawn in this colour at half its strength
+var maskcolor = color.NRGBA{R: 255, G: 0, B: 160}
+
+// maskoverlay tints what the mask covers so it can be drawn over the image
+func maskoverlay(mask image.Image) *image.NRGBA {
+	overlay := image.NewNRGBA(mask.Bounds())
+	paintoverlay(overlay, mask, mask.Bounds())
+	return overlay
+}
+
+// paintoverlay redoes the part r of the overlay
+func paintoverlay(overlay *image.NRGBA, mask image.Image, r image.Rectangle) {
+	r = r.Intersect(mask.Bounds())
+	gray, isgray := mask.(*image.Gray)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			var v uint8
+			if isgray {
+				v = gray.GrayAt(x, y).Y
+			} else {
+				v = color.GrayModel.Convert(mask.At(x, y)).(color.Gray).Y
+			}
+			c := maskcolor
+			c.A = v / 2
+			overlay.SetNRGBA(x, y, c)
+		}
+	}
+}
+
+type masktool int
+
+const (
+	maskbrush masktool = iota
+	maskeraser
+	maskfill
+)
+
+// maskcanvas shows an image with its mask on top and paints into the mask
+type maskcanvas struct {
+	widget.BaseWidget
+	image   *canvas.Image
+	overlay *canvas.Image
+
+	mask *image.Gray
+	tint *image.NRGBA
+
+	tool masktool
+	// brush radius in screen units, so it feels the same at every zoom
+	radius float32
+	// where the last dab of the current stroke was
+	last    *image.Point
+	changed bool
+}
+
+var _ fyne.Draggable = (*maskcanvas)(nil)
+var _ fyne.Tappable = (*maskcanvas)(nil)
+
+func newmaskcanvas() *maskcanvas {
+	mc := &maskcanvas{
+		image:   canvas.NewImageFromResource(theme.FileImageIcon()),
+		overlay: canvas.NewImageFromImage(nil),
+		radius:  20,
+	}
+	for _, img := range []*canvas.Image{mc.image, mc.overlay} {
+		img.FillMode = canvas.ImageFillContain
+		img.ScaleMode = canvas.ImageScaleSmooth
+	}
+	mc.ExtendBaseWidget(mc)
+	return mc
+}
+
+// SetImage shows img with mask on top, mask is scaled to the size of img and may be nil
+func (mc *maskcanvas) SetImage(img, mask image.Image) {
+	b := img.Bounds()
+	mc.mask = image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()))
+	if mask != nil {
+		xdraw.NearestNeighbor.Scale(mc.mask, mc.mask.Bounds(), mask, mask.Bounds(), draw.Src, nil)
+	}
+	mc.tint = maskoverlay(mc.mask)
+
+	mc.image.Resource = nil
+	mc.image.Image = img
+	mc.overlay.Image = mc.tint
+	mc.changed = false
+	mc.image.Refresh()
+	mc.overlay.Refresh()
+}
+
+// Clear removes everything from the mask
+func (mc *maskcanvas) Clear() {
+	if mc.mask == nil {
+		return
+	}
+	clear(mc.mask.Pix)
+	mc.update(mc.mask.Bounds())
+}
+
+// topixel turns a position on the widget into one on the image, the image is letterboxed
+func (mc *maskcanvas) topixel(pos fyne.Position) (image.Point, float32) {
+	size := mc.Size()
+	w, h := float32(mc.mask.Bounds().Dx()), float32(mc.mask.Bounds().Dy())
+	scale := min(size.Width/w, size.Height/h)
+	offx := (size.Width - w*scale) / 2
+	offy := (size.Height - h*scale) / 2
+	return image.Pt(int((pos.X-offx)/scale), int((pos.Y-offy)/scale)), scale
+}
+
+func (mc *maskcanvas) Tapped(ev *fyne.PointEvent) {
+	if mc.mask == nil {
+		return
+	}
+	pt, scale := mc.topixel(ev.Position)
+	if mc.tool == maskfill {
+		mc.fill(pt)
+		return
+	}
+	mc.dab(pt, int(mc.radius/scale))
+}
+
+func (mc *maskcanvas) Dragged(ev *fyne.DragEvent) {
+	if mc.mask == nil || mc.tool == maskfill {
+		return
+	}
+	pt, scale := mc.topixel(ev.Position)
+	radius := int(mc.radius / scale)
+	if mc.last == nil {
+		mc.dab(pt, radius)
+	} else {
+		// fill the gaps between the events
+		from := *mc.last
+		dist := math.Hypot(float64(pt.X-from.X), float64(pt.Y-from.Y))
+		steps := int(dist/max(float64(radius)/2, 1)) + 1
+		for i := 1; i <= steps; i++ {
+			t := float64(i) / float64(steps)
+			mc.dab(image.Pt(from.X+int(t*float64(pt.X-from.X)), from.Y+int(t*float64(pt.Y-from.Y))), radius)
+		}
+	}
+	mc.last = &pt
+}
+
+func (mc *maskcanvas) DragEnd() {
+	mc.last = nil
+}
+
+// dab paints or erases a circle
+func (mc *maskcanvas) dab(center image.Point, radius int) {
+	radius = max(radius, 1)
+	var v uint8 = 255
+	if mc.tool == maskeraser {
+		v = 0
+	}
+	r := image.Rect(center.X-radius, center.Y-radius, center.X+radius+1, center.Y+radius+1).Intersect(mc.mask.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			dx, dy := x-center.X, y-center.Y
+			if dx*dx+dy*dy <= radius*radius {
+				mc.mask.SetGray(x, y, color.Gray{Y: v})
+			}
+		}
+	}
+	mc.update(r)
+}
+
+// fill covers the area around pt that has the same value as pt
+func (mc *maskcanvas) fill(pt image.Point) {
+	if !pt.In(mc.mask.Bounds()) {
+		return
+	}
+	from := mc.mask.GrayAt(pt.X, pt.Y).Y
+	if from == 255 {
+		return
+	}
+
+	// pixels are painted when they are queued so none is queued twice
+	dirty := image.Rectangle{Min: pt, Max: pt.Add(image.Pt(1, 1))}
+	mc.mask.SetGray(pt.X, pt.Y, color.Gray{Y: 255})
+	stack := []image.Point{pt}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		dirty = dirty.Union(image.Rectangle{Min: p, Max: p.Add(image.Pt(1, 1))})
+		for _, n := range []image.Point{{p.X + 1, p.Y}, {p.X - 1, p.Y}, {p.X, p.Y + 1}, {p.X, p.Y - 1}} {
+			if n.In(mc.mask.Bounds()) && mc.mask.GrayAt(n.X, n.Y).Y == from {
+				mc.mask.SetGray(n.X, n.Y, color.Gray{Y: 255})
+				stack = append(stack, n)
+			}
+		}
+	}
+	mc.update(dirty)
+}
+
+func (mc *maskcanvas) update(r image.Rectangle) {
+	paintoverlay(mc.tint, mc.mask, r)
+	mc.changed = true
+	mc.overlay.Refresh()
+}
+
+func (mc *maskcanvas) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(container.NewStack(mc.image, mc.overlay))
+}
+
+// paintmask opens a window to paint the mask of the entry at index.
+// saved gets the new mask once it is on disk and where the entry is by then.
+func (g *gui) paintmask(p *projectStructure, index int, saved func(index int, mask image.Image)) {
+	e := &p.Entries[index]
+	imagepath := e.ImagePath
+	path := p.MaskPath(e)
+	w := g.a.NewWindow("Mask - " + e.Name())
+
+	mc := newmaskcanvas()
+
+	tools := widget.NewRadioGroup([]string{"Brush", "Eraser", "Fill"}, func(s string) {
+		switch s {
+		case "Eraser":
+			mc.tool = maskeraser
+		case "Fill":
+			mc.tool = maskfill
+		default:
+			mc.tool = maskbrush
+		}
+	})
+	tools.Horizontal = true
+	tools.Required = true
+	tools.SetSelected("Brush")
+
+	sizelabel := widget.NewLabel("")
+	size := widget.NewSlider(1, 200)
+	size.OnChanged = func(f float64) {
+		mc.radius = float32(f)
+		sizelabel.SetText(fmt.Sprintf("%0.0f", f))
+	}
+	size.SetValue(float64(mc.radius))
+
+	clearbutton := widget.NewButtonWithIcon("Clear", theme.ContentClearIcon(), mc.Clear)
+	savebutton := widget.NewButtonWithIcon("Save", theme.DocumentSaveIcon(), func() {
+		if mc.mask == nil {
+			return
+		}
+		// the window stays open while entries are removed
+		index := p.entryindex(imagepath)
+		if index < 0 {
+			dialog.ShowInformation("Mask", "The image was removed from the project", w)
+			return
+		}
+		err := dataset.SaveMask(path, mc.mask)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		p.history.SetMask(index, path)
+		mc.changed = false
+		saved(index, mc.mask)
+		w.Close()
+	})
+	savebutton.Importance = widget.HighImportance
+
+	w.SetCloseIntercept(func() {
+		if !mc.changed {
+			w.Close()
+			return
+		}
+		dialog.ShowConfirm("Discard Mask", "The mask was changed, close without saving it?", func(b bool) {
+			if b {
+				w.Close()
+			}
+		}, w)
+	})
+
+	toolbar := container.NewBorder(nil, nil,
+		tools,
+		container.NewHBox(clearbutton, savebutton),
+		container.NewBorder(nil, nil, widget.NewLabel("Size"), sizelabel, size),
+	)
+	w.SetContent(container.NewBorder(toolbar, nil, nil, nil, mc))
+	w.Resize(fyne.NewSize(1024, 768))
+	w.Show()
+
+	maskpath := e.Mask
+	go func() {
+		img, err := decodeimagefile(imagepath)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		var mask image.Image
+		if maskpath != nil {
+			mask, err = decodeimagefile(*maskpath)
+			if err != nil {
+				// start over with an empty one
+				dialog.ShowError(err, w)
+			}
+		}
+		mc.SetImage(img, mask)
+	}()
+}
@@ -1,194 +1,440 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io"
+	"image"
+	"maps"
+	"path/filepath"
 	"slices"
-	"strings"
+	"sync"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
-	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+
+	"biehdc.priv.aidatasetmanager/dataset"
 )
 
-type imageEntry struct {
-	ImagePath fyne.URI
-	Tags      []string
-	//
-	loadedImage *ImageHighlightable
-	// for jsonl to jsonl only
-	mask *string
+type projectStructure struct {
+	*dataset.Project
+	history *dataset.History
+	// decoded images by path
+	loadedImages map[string]*ImageHighlightable
+	// files that could not be loaded, images that fail to decode are added later
+	problems []dataset.Problem
+	// the last quality check by path, nil until one ran.
+	// it is replaced as a whole and never changed after.
+	lint map[string]*dataset.LintResult
 }
 
-type projectStructure struct {
-	parentdir fyne.ListableURI
-	data      []imageEntry
+// marksaved is called once the tags are on disk
+func (p *projectStructure) marksaved() {
+	p.history.MarkSaved()
+	err := dataset.RemoveJournal(p.Source)
+	if err != nil {
+		fyne.LogError("could not remove recovery journal", err)
+	}
 }
 
-type jsonlentry struct {
-	Image string  `json:"image"`
-	Text  string  `json:"text"`
-	Mask  *string `json:"mask"`
+func (p *projectStructure) loadedImage(i int) *ImageHighlightable {
+	return p.loadedImages[p.Entries[i].ImagePath]
 }
 
-func (g *gui) save(p *projectStructure, cb func(error)) {
+// entryindex finds the entry of an image again, removing entries moves the others.
+// -1 if it is not in the project anymore.
+func (p *projectStructure) entryindex(path string) int {
+	return slices.IndexFunc(p.Entries, func(e dataset.Entry) bool { return e.ImagePath == path })
+}
+
+func (g *gui) save(p *projectStructure, cb func(summary string, err error)) {
 	var d dialog.Dialog
 
 	var errs []error
+	var summary string
+	exporting := false
 	closefunc := func() {
-		cb(errors.Join(errs...))
+		if exporting {
+			return // a copy, the tags are still unsaved
+		}
+		cb(summary, errors.Join(errs...))
 	}
 
+	relativepaths := widget.NewCheck("Relative paths", func(b bool) {
+		g.a.Preferences().SetBool("relativepaths", b)
+	})
+	relativepaths.Checked = g.a.Preferences().Bool("relativepaths")
+
 	asjsonl := widget.NewButton(".jsonl file", func() {
-		jsonlfile, err := storage.Child(p.parentdir, p.parentdir.Name()+".jsonl")
+		err := dataset.SaveJSONL(p.Project, p.JSONLPath(), dataset.JSONLOptions{RelativePaths: relativepaths.Checked})
 		if err != nil {
-			cb(err)
-			return
+			errs = append(errs, err)
+		} else {
+			p.marksaved()
+			summary = fmt.Sprintf("%d entries written to %s", len(p.Entries), filepath.Base(p.JSONLPath()))
 		}
+		d.Hide()
+	})
 
-		jfw, err := storage.Writer(jsonlfile)
+	asmetadata := widget.NewButton(dataset.MetadataName, func() {
+		err := dataset.SaveMetadata(p.Project, p.MetadataPath())
 		if err != nil {
-			cb(err)
-			return
+			errs = append(errs, err)
+		} else {
+			p.marksaved()
+			summary = fmt.Sprintf("%d entries written to %s", len(p.Entries), dataset.MetadataName)
 		}
-		defer jfw.Close()
-
-		for _, d := range p.data {
-			entry := jsonlentry{
-				Image: d.ImagePath.Path(),
-				Text:  strings.Join(d.Tags, ", "),
-				Mask:  d.mask,
-			}
+		d.Hide()
+	})
 
-			str, err := json.Marshal(entry)
-			if err != nil {
-				errs = append(errs, err)
-				continue
-			}
+	skipuntagged := widget.NewCheck("Skip untagged", func(b bool) {
+		g.a.Preferences().SetBool("skipuntagged", b)
+	})
+	skipuntagged.Checked = g.a.Preferences().Bool("skipuntagged")
 
-			jfw.Write(str)
-			jfw.Write([]byte("\n"))
+	asdir := widget.NewButton(".txt files", func() {
+		txtsummary, err := dataset.SaveTxt(p.Project, dataset.TxtOptions{SkipUntagged: skipuntagged.Checked})
+		// crops only fit into the jsonl files, so the project stays unsaved while it has some
+		cropped := p.Cropped()
+		if err != nil {
+			errs = append(errs, err)
+		} else if cropped < 1 {
+			p.marksaved()
+		}
+		summary = txtsummary.String()
+		if cropped > 0 {
+			summary += fmt.Sprintf("\n%d crops can not be kept in .txt files, save as .jsonl or %s to keep them", cropped, dataset.MetadataName)
 		}
 		d.Hide()
 	})
 
-	asdir := widget.NewButton(".txt files", func() {
-		for _, d := range p.data {
-			txtfilename := strings.TrimSuffix(d.ImagePath.Name(), d.ImagePath.Extension()) + ".txt"
-
-			uri, err := storage.Child(p.parentdir, txtfilename)
-			if err != nil {
-				errs = append(errs, err)
-				continue
-			}
-
-			uwc, err := storage.Writer(uri)
-			if err != nil {
-				errs = append(errs, err)
-				continue
-			}
-
-			_, err = io.WriteString(uwc, strings.Join(d.Tags, ", "))
-			if err != nil {
-				errs = append(errs, err)
-				//continue
-			}
-
-			uwc.Close()
-		}
+	asexport := widget.NewButton("Export resized", func() {
+		exporting = true
 		d.Hide()
+		g.exportproject(p)
 	})
 
-	d = dialog.NewCustom("Save as", "Ok", container.NewGridWithColumns(2, asjsonl, asdir), g.w)
+	d = dialog.NewCustom("Save as", "Ok", container.NewGridWithColumns(4, container.NewVBox(asjsonl, relativepaths), asmetadata, container.NewVBox(asdir, skipuntagged), asexport), g.w)
 	d.SetOnClosed(closefunc)
 	d.Show()
 	d.Resize(d.MinSize().Add(d.MinSize()))
 }
 
-func (g *gui) saveDialogErrorAndCallbackOnSuccess(p *projectStructure, cb func()) {
-	g.save(p, func(err error) {
+func (g *gui) saveDialogErrorAndCallbackOnSuccess(p *projectStructure, cb func(summary string)) {
+	g.save(p, func(summary string, err error) {
 		if err != nil {
 			dialog.ShowError(err, g.w)
 		} else {
-			cb()
+			cb(summary)
 		}
 	})
 }
 
-func collecttags(in []imageEntry) (tags []string) {
-	// grab all tags
-	var tagsc []string
-	for _, t := range in {
-		tagsc = append(tagsc, t.Tags...)
-	}
-	// count them
-	collect := make(map[string]int)
-	for _, t := range tagsc {
-		tag, _ := collect[t]
-		tag++
-		collect[t] = tag
-	}
-	// sort them
-	type tag_tmp struct {
-		tag   string
-		count int
-	}
-	var tagged []tag_tmp
-	for ttag, count := range collect {
-		tagged = append(tagged, tag_tmp{tag: ttag, count: count})
-	}
-	slices.SortFunc(tagged, func(a, b tag_tmp) int {
-		res := b.count - a.count
-		if res == 0 {
-			// makes the list stable
-			res = strings.Compare(a.tag, b.tag)
-		}
-		return res
-	})
-	// copy them over
-	for _, data := range tagged {
-		tags = append(tags, data.tag)
-	}
-	return tags
-}
-
-func sliceAppendNoDupes(s []string, ss string) []string {
-	for _, option := range s {
-		if option == ss {
-			// already exists, bail
-			return s
-		}
-	}
-
-	// was not found, append
-	return append(s, ss)
+// pinned tags are remembered for each dataset
+func pinnedkey(source string) string {
+	return "pinned " + source
 }
 
 func (g *gui) projectview(p projectStructure) fyne.CanvasObject {
+	p.history = dataset.NewHistory(p.Project)
+
+	// the journal lives as long as the window, it is stopped before the project goes away
+	stopautosave := func() {}
 	g.w.SetCloseIntercept(func() {
+		if !p.history.Dirty() {
+			stopautosave()
+			g.w.Close()
+			return
+		}
 		dialog.ShowConfirm("Save Changes", "Do you want to save your changes?", func(b bool) {
 			if b {
-				g.saveDialogErrorAndCallbackOnSuccess(&p, func() { g.w.Close() })
+				g.saveDialogErrorAndCallbackOnSuccess(&p, func(summary string) {
+					if p.history.Dirty() {
+						// nothing was picked or not everything could be kept
+						if summary != "" {
+							dialog.ShowInformation("Not Everything Saved", summary, g.w)
+						}
+						return
+					}
+					stopautosave()
+					g.w.Close()
+				})
 			} else {
+				// they did not want them, so there is nothing to recover
+				stopautosave()
+				dataset.RemoveJournal(p.Source)
 				g.w.Close()
 			}
 		}, g.w)
 	})
 
+	griditemsize := float32(g.a.Preferences().IntWithFallback("griditemsize", 256))
+	p.Pinned = g.a.Preferences().StringList(pinnedkey(p.Source))
+
+	// everything shows a placeholder until its thumbnail is ready
+	p.loadedImages = make(map[string]*ImageHighlightable, len(p.Entries))
+	paths := make([]string, 0, len(p.Entries))
+	// set up once the list exists, the entry is looked up by path as removing entries moves them
+	var openviewer func(id int)
+	for _, e := range p.Entries {
+		path := e.ImagePath
+		nih := newthumbnailimage(griditemsize)
+		nih.OnDoubleTapped = func() {
+			if id := p.entryindex(path); id >= 0 {
+				openviewer(id)
+			}
+		}
+		p.loadedImages[path] = nih
+		paths = append(paths, path)
+	}
+	// the thumbnail workers add to the problems
+	var problemsmu sync.Mutex
+	problemsbutton := widget.NewButtonWithIcon("", theme.WarningIcon(), func() {
+		problemsmu.Lock()
+		problems := slices.Clone(p.problems)
+		problemsmu.Unlock()
+		g.showproblems(problems)
+	})
+	problemsbutton.Importance = widget.WarningImportance
+	updateproblems := func() {
+		problemsmu.Lock()
+		count := len(p.problems)
+		problemsmu.Unlock()
+		problemsbutton.SetText(fmt.Sprint(count))
+		if count > 0 {
+			problemsbutton.Show()
+		} else {
+			problemsbutton.Hide()
+		}
+	}
+	updateproblems()
+
+	addproblem := func(path string, err error) {
+		// they are done again after resizing the grid
+		problemsmu.Lock()
+		known := slices.ContainsFunc(p.problems, func(problem dataset.Problem) bool { return problem.Path == path })
+		if !known {
+			p.problems = append(p.problems, dataset.NewProblem(err))
+		}
+		problemsmu.Unlock()
+		if !known {
+			updateproblems()
+		}
+	}
+
+	// only thumbnails are kept, so they are redone when the grid size changes
+	// stopping waits for the workers, their callbacks read loadedImages which removing entries changes
+	stopthumbnails := func() {}
+	loadthumbnails := func() {
+		stopthumbnails()
+		ctx, cancel := context.WithCancel(context.Background())
+		thumbnails := newthumbnailer(int(griditemsize))
+		// the crops need the size of the original to be placed on the thumbnail
+		crops := make(map[string]*image.Rectangle)
+		for _, e := range p.Entries {
+			if e.Crop != nil {
+				crops[e.ImagePath] = e.Crop
+			}
+		}
+		waitimages := thumbnails.loadall(ctx, paths, func(path string, img image.Image, err error) {
+			ih, ok := p.loadedImages[path]
+			if !ok {
+				return // removed in the meantime
+			}
+			if err != nil {
+				ih.SetResource(theme.BrokenImageIcon())
+				addproblem(path, err)
+				return
+			}
+			ih.SetImage(img)
+			if crop, ok := crops[path]; ok {
+				width, height, err := dataset.ImageSize(path)
+				if err == nil {
+					ih.SetCrop(crop, width, height)
+				}
+			}
+		})
+		// the masks go on top of the images they belong to
+		masks := make(map[string]string)
+		for _, e := range p.Entries {
+			if e.Mask != nil {
+				masks[*e.Mask] = e.ImagePath
+			}
+		}
+		waitmasks := thumbnails.loadall(ctx, slices.Collect(maps.Keys(masks)), func(path string, img image.Image, err error) {
+			if err != nil {
+				addproblem(path, err)
+				return
+			}
+			if ih, ok := p.loadedImages[masks[path]]; ok {
+				ih.SetMask(img)
+			}
+		})
+		stopthumbnails = func() {
+			cancel()
+			waitimages()
+			waitmasks()
+		}
+	}
+	loadthumbnails()
+
+	// the quality check hands its results over from its own goroutine
+	var lintmu sync.Mutex
 	var imagelist *widget.List
 	currentselectedimageid := -1
-	alltagslist := widget.NewCheckGroup(collecttags(p.data), func(s []string) {
-		// assign tags to current selected image
+	selectedindexes := make(map[widget.ListItemID]struct{})
+	var alltagslist *TagGroup
+	var tagorder *TagOrder
+	info := container.NewVBox()
+
+	// the list only shows the entries matching the filter, these are their indexes
+	shown := make([]int, len(p.Entries))
+	shownpos := make(map[int]widget.ListItemID, len(p.Entries))
+	for i := range shown {
+		shown[i] = i
+		shownpos[i] = i
+	}
+	refreshentry := func(id int) {
+		if pos, ok := shownpos[id]; ok {
+			imagelist.RefreshItem(pos)
+		}
+	}
+
+	// in multi edit mode tag changes go to every selected image, otherwise only to the current one
+	multiedit := widget.NewCheck("Multi Edit", nil)
+	targets := func() []int {
+		if multiedit.Checked && len(selectedindexes) > 0 {
+			return slices.Sorted(maps.Keys(selectedindexes))
+		}
 		if currentselectedimageid >= 0 {
-			p.data[currentselectedimageid].Tags = s
-			imagelist.RefreshItem(currentselectedimageid)
+			return []int{currentselectedimageid}
+		}
+		return nil
+	}
+	describetargets := func(ids []int) string {
+		if len(ids) == 1 {
+			return p.Entries[ids[0]].Name()
+		}
+		return fmt.Sprintf("%d images", len(ids))
+	}
+	showcurrenttags := func() {
+		ids := targets()
+		counts := make(map[string]int)
+		for _, id := range ids {
+			for _, tag := range p.Entries[id].Tags {
+				counts[tag]++
+			}
+		}
+		alltagslist.SetSelected(counts, len(ids))
+		// the order only makes sense for a single image
+		if len(ids) == 1 {
+			tagorder.SetTags(p.Entries[ids[0]].Tags, p.Pinned)
+			info.Objects = []fyne.CanvasObject{entryinfo(p.Project, &p.Entries[ids[0]])}
+		} else {
+			tagorder.SetTags(nil, nil)
+			info.Objects = nil
 		}
+		info.Refresh()
+	}
+	multiedit.OnChanged = func(bool) { showcurrenttags() }
+
+	history := p.history
+	historylabel := widget.NewLabel("")
+	historylabel.Truncation = fyne.TextTruncateEllipsis
+	var undobutton, redobutton *widget.Button
+	updatehistory := func() {
+		if edit := history.NextUndo(); edit != nil {
+			historylabel.SetText("Undo: " + edit.Name)
+			undobutton.Enable()
+		} else {
+			historylabel.SetText("")
+			undobutton.Disable()
+		}
+		if history.NextRedo() != nil {
+			redobutton.Enable()
+		} else {
+			redobutton.Disable()
+		}
+		g.settitle(&p)
+	}
+	// edits can touch any entry, so everything that shows tags is redone
+	afteredit := func() {
+		// undoing a rename brings back tags that are not listed anymore
+		for _, tag := range p.CollectTags() {
+			alltagslist.Append(tag)
+		}
+		imagelist.Refresh()
+		showcurrenttags()
+	}
+	undo := func() {
+		if history.Undo() != nil {
+			afteredit()
+		}
+	}
+	redo := func() {
+		if history.Redo() != nil {
+			afteredit()
+		}
+	}
+	undobutton = widget.NewButtonWithIcon("", theme.ContentUndoIcon(), undo)
+	redobutton = widget.NewButtonWithIcon("", theme.ContentRedoIcon(), redo)
+	updatehistory()
+	history.OnChanged = updatehistory
+	g.w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyZ, Modifier: fyne.KeyModifierShortcutDefault}, func(fyne.Shortcut) { undo() })
+	g.w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyZ, Modifier: fyne.KeyModifierShortcutDefault | fyne.KeyModifierShift}, func(fyne.Shortcut) { redo() })
+
+	alltagslist = NewTagGroup(p.CollectTags(), func(tag string, checked bool) {
+		ids := targets()
+		if len(ids) > 0 {
+			var err error
+			if checked {
+				_, err = history.Do(fmt.Sprintf("add %q to %s", tag, describetargets(ids)), ids, func(e *dataset.Entry) error {
+					return e.AddTag(tag)
+				})
+			} else {
+				_, err = history.Do(fmt.Sprintf("remove %q from %s", tag, describetargets(ids)), ids, func(e *dataset.Entry) error {
+					e.RemoveTag(tag)
+					return nil
+				})
+			}
+			if err != nil {
+				dialog.ShowError(err, g.w)
+			}
+			for _, id := range ids {
+				refreshentry(id)
+			}
+		}
+		// partial ones are now checked, and nothing stays checked without a target
+		showcurrenttags()
+	})
+	tagorder = NewTagOrder(func(tags []string) {
+		ids := targets()
+		if len(ids) != 1 {
+			return
+		}
+		_, err := history.Do(fmt.Sprintf("reorder tags of %s", describetargets(ids)), ids, func(e *dataset.Entry) error {
+			return e.SetTags(tags)
+		})
+		if err != nil {
+			dialog.ShowError(err, g.w)
+		}
+		showcurrenttags()
+	}, func(tag string) {
+		if i := slices.Index(p.Pinned, tag); i >= 0 {
+			p.Pinned = slices.Delete(p.Pinned, i, i+1)
+		} else {
+			p.Pinned = append(p.Pinned, tag)
+		}
+		g.a.Preferences().SetStringList(pinnedkey(p.Source), p.Pinned)
+		showcurrenttags()
 	})
+	pinnedhint := widget.NewLabel("Drag to reorder, pinned tags are written first")
+	pinnedhint.Truncation = fyne.TextTruncateEllipsis
+
 	defaultcolumns := g.a.Preferences().IntWithFallback("numcolums", 2)
 	alltagslist.SetColumns(defaultcolumns)
 
@@ -197,48 +443,64 @@ func (g *gui) projectview(p projectStructure) fyne.CanvasObject {
 	addtag.TextStyle = fyne.TextStyle{Monospace: true}
 	addtag.ActionItem = widget.NewButtonWithIcon("", theme.ConfirmIcon(), func() { addtag.OnSubmitted(addtag.Text) })
 	addtag.OnSubmitted = func(s string) {
-		s = strings.TrimSpace(s)
-		if s == "" {
+		s, err := dataset.NormaliseTag(s)
+		if err != nil {
 			return // we dont need an empty tag
 		}
-		// add tag to tag list and apply it to current selected image if any, or all of them
+		// add tag to tag list and apply it to the selected images if any, or all shown ones
 		alltagslist.Append(s)
 
+		addit := func(e *dataset.Entry) error { return e.AddTag(s) }
 		if addtoall.Checked {
-			for i := range p.data {
-				p.data[i].Tags = sliceAppendNoDupes(p.data[i].Tags, s)
+			// all means everything the filter lets through
+			name := fmt.Sprintf("add %q to all images", s)
+			if len(shown) != len(p.Entries) {
+				name = fmt.Sprintf("add %q to all %d shown images", s, len(shown))
 			}
-		} else if currentselectedimageid >= 0 {
-			p.data[currentselectedimageid].Tags = sliceAppendNoDupes(p.data[currentselectedimageid].Tags, s)
+			_, err = history.Do(name, slices.Clone(shown), addit)
+		} else if ids := targets(); len(ids) > 0 {
+			_, err = history.Do(fmt.Sprintf("add %q to %s", s, describetargets(ids)), ids, addit)
+		}
+		if err != nil {
+			dialog.ShowError(err, g.w)
 		}
 		addtag.TypedShortcut(&fyne.ShortcutSelectAll{})
 
-		if currentselectedimageid >= 0 {
-			alltagslist.SetSelected(p.data[currentselectedimageid].Tags)
-		}
+		afteredit()
 	}
 
-	griditemsize := float32(g.a.Preferences().IntWithFallback("griditemsize", 256))
 	imageviewer := container.NewGridWrap(fyne.NewSquareSize(griditemsize))
 	imageviewercontainer := container.NewVScroll(imageviewer)
 
-	selectedindexes := make(map[widget.ListItemID]struct{})
 	imagelist = widget.NewList(
 		// length
-		func() int { return len(p.data) },
+		func() int { return len(shown) },
 		// create
 		func() fyne.CanvasObject {
-			return widget.NewLabel("averagefilename.len")
+			issues := widget.NewIcon(theme.WarningIcon())
+			issues.Hide()
+			return container.NewBorder(nil, nil, nil, issues, widget.NewLabel("averagefilename.len"))
 		},
 		// update
 		func(lii widget.ListItemID, co fyne.CanvasObject) {
-			label, ok := co.(*widget.Label)
+			row, ok := co.(*fyne.Container)
 			if !ok {
 				return
 			}
+			label, issues := row.Objects[0].(*widget.Label), row.Objects[1].(*widget.Icon)
+			id := shown[lii]
+
+			lintmu.Lock()
+			r := p.lint[p.Entries[id].ImagePath]
+			lintmu.Unlock()
+			if r != nil && len(r.Issues) > 0 {
+				issues.Show()
+			} else {
+				issues.Hide()
+			}
 
-			_, isSelected := selectedindexes[lii]
-			if lii == currentselectedimageid {
+			_, isSelected := selectedindexes[id]
+			if id == currentselectedimageid {
 				label.Importance = widget.DangerImportance
 			} else if isSelected {
 				label.Importance = widget.SuccessImportance
@@ -246,45 +508,228 @@ func (g *gui) projectview(p projectStructure) fyne.CanvasObject {
 				label.Importance = widget.MediumImportance
 			}
 
-			label.SetText(fmt.Sprintf("%s (%d)", p.data[lii].ImagePath.Name(), len(p.data[lii].Tags)))
+			label.SetText(fmt.Sprintf("%s (%d)", p.RelPath(&p.Entries[id]), len(p.Entries[id].Tags)))
 		},
 	)
 
 	swapselected := func(id widget.ListItemID) {
 		if currentselectedimageid >= 0 {
-			p.data[currentselectedimageid].loadedImage.SetHighlight(false)
+			p.loadedImage(currentselectedimageid).SetHighlight(false)
 		}
-		p.data[id].loadedImage.SetHighlight(true)
+		p.loadedImage(id).SetHighlight(true)
 		currentselectedimageid = id
 	}
 
-	imagelist.OnSelected = func(id widget.ListItemID) {
+	unselect := func(id int) {
+		delete(selectedindexes, id)
+		imageviewer.Remove(p.loadedImage(id))
+		if id == currentselectedimageid {
+			p.loadedImage(id).SetHighlight(false)
+			currentselectedimageid = -1
+		}
+	}
+
+	imagelist.OnSelected = func(lii widget.ListItemID) {
+		id := shown[lii]
 		_, wasSelected := selectedindexes[id]
 		if wasSelected {
 			if id != currentselectedimageid {
 				// promote previously selected to current selected
 				swapselected(id)
-				alltagslist.SetSelected(p.data[id].Tags)
+				showcurrenttags()
 			} else {
 				// demote to unselected
-				delete(selectedindexes, id)
-				imageviewer.Remove(p.data[id].loadedImage)
-				currentselectedimageid = -1
-				alltagslist.SetSelected(nil) // clear
+				unselect(id)
+				showcurrenttags()
 			}
 		} else {
 			// promote to current selected
 			selectedindexes[id] = struct{}{}
 			swapselected(id)
-			imageviewer.Add(p.data[id].loadedImage)
+			imageviewer.Add(p.loadedImage(id))
 			imageviewercontainer.ScrollToBottom()
-			alltagslist.SetSelected(p.data[id].Tags)
+			showcurrenttags()
+		}
+		imagelist.Unselect(lii)
+		imagelist.RefreshItem(lii)
+		imageviewercontainer.Refresh()
+	}
+
+	// the viewer follows the list, the image it shows is the one the tags are edited for
+	var imagewindow *viewer
+	viewedid := -1
+	// if the viewer selected the image it shows, it also takes it away again
+	viewerselected := false
+	showinviewer := func(id int) {
+		if viewerselected && viewedid != id && viewedid < len(p.Entries) {
+			unselect(viewedid)
 		}
-		imagelist.Unselect(id)
-		imagelist.RefreshItem(id)
+		_, wasSelected := selectedindexes[id]
+		viewerselected = !wasSelected
+		if !wasSelected {
+			selectedindexes[id] = struct{}{}
+			imageviewer.Add(p.loadedImage(id))
+		}
+		swapselected(id)
+		viewedid = id
+		imagelist.Refresh()
 		imageviewercontainer.Refresh()
+		showcurrenttags()
+		imagewindow.open(p.Entries[id].ImagePath)
+	}
+	openviewer = func(id int) {
+		if imagewindow == nil {
+			imagewindow = g.newviewer(func(delta int) bool {
+				pos, ok := shownpos[viewedid]
+				if !ok || pos+delta < 0 || pos+delta >= len(shown) {
+					return false
+				}
+				showinviewer(shown[pos+delta])
+				return true
+			})
+			imagewindow.w.SetOnClosed(func() {
+				imagewindow = nil
+				viewedid = -1
+				viewerselected = false
+			})
+		}
+		// opening it from the grid keeps the selection as it is
+		viewerselected = false
+		viewedid = id
+		showinviewer(id)
 	}
 
+	// the filter is only applied when it changes, so images do not vanish while they are edited
+	filter := widget.NewEntry()
+	filter.SetPlaceHolder("Filter, e.g. 1girl AND NOT outdoors, untagged, tags<3, file:*.png")
+	filterstatus := widget.NewLabel("")
+	showfiltered := func(q *dataset.Query) {
+		shown = p.Filter(q)
+		clear(shownpos)
+		for pos, id := range shown {
+			shownpos[id] = pos
+		}
+		// selection only covers what is shown
+		for id := range selectedindexes {
+			if _, ok := shownpos[id]; !ok {
+				unselect(id)
+			}
+		}
+
+		filterstatus.Importance = widget.MediumImportance
+		filterstatus.SetText(fmt.Sprintf("%d / %d", len(shown), len(p.Entries)))
+		imagelist.Refresh()
+		imageviewercontainer.Refresh()
+		showcurrenttags()
+	}
+	applyfilter := func() {
+		q, err := dataset.ParseQuery(filter.Text)
+		if err != nil {
+			var qerr *dataset.QueryError
+			if errors.As(err, &qerr) {
+				filterstatus.Importance = widget.DangerImportance
+				filterstatus.SetText(qerr.Msg)
+			}
+			return
+		}
+		showfiltered(q)
+	}
+	filter.OnChanged = func(string) { applyfilter() }
+	filter.OnSubmitted = func(string) { applyfilter() }
+	applyfilter()
+
+	managetags := widget.NewButtonWithIcon("", theme.ListIcon(), func() {
+		g.managetags(&p, func() {
+			// drop what got renamed away
+			alltagslist.SetOptions(p.CollectTags())
+			afteredit()
+		})
+	})
+
+	// removing entries changes every index, so everything that holds one starts over
+	removeentries := func(ids []int) {
+		if len(ids) < 1 {
+			return
+		}
+		stopthumbnails()
+		for id := range selectedindexes {
+			unselect(id)
+		}
+		// the selection the viewer made is gone with the rest
+		viewerselected = false
+		viewedpath := ""
+		if viewedid >= 0 && viewedid < len(p.Entries) {
+			viewedpath = p.Entries[viewedid].ImagePath
+		}
+		for _, id := range ids {
+			delete(p.loadedImages, p.Entries[id].ImagePath)
+		}
+		history.Remove(ids)
+		paths = paths[:0]
+		for _, e := range p.Entries {
+			paths = append(paths, e.ImagePath)
+		}
+		loadthumbnails()
+
+		// the viewer keeps its image under the new index or closes if it was removed
+		viewedid = slices.Index(paths, viewedpath)
+		if viewedid < 0 && imagewindow != nil {
+			imagewindow.w.Close()
+		}
+
+		// a broken filter would keep the old indexes
+		q, err := dataset.ParseQuery(filter.Text)
+		if err != nil {
+			filter.SetText("")
+		} else {
+			showfiltered(q)
+		}
+		alltagslist.SetOptions(p.CollectTags())
+		afteredit()
+	}
+
+	duplicates := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
+		g.findduplicates(&p, int(griditemsize), removeentries)
+	})
+
+	lint := widget.NewButtonWithIcon("", theme.VisibilityIcon(), func() {
+		lintmu.Lock()
+		last := p.lint
+		lintmu.Unlock()
+		g.lintproject(&p, last, func(results map[string]*dataset.LintResult) {
+			lintmu.Lock()
+			p.lint = results
+			lintmu.Unlock()
+			imagelist.Refresh()
+		})
+	})
+
+	buckets := widget.NewButtonWithIcon("", theme.GridIcon(), func() {
+		g.showbuckets(&p)
+	})
+
+	paintmask := widget.NewButtonWithIcon("", theme.ColorPaletteIcon(), func() {
+		id := currentselectedimageid
+		if id < 0 {
+			return
+		}
+		g.paintmask(&p, id, func(id int, mask image.Image) {
+			p.loadedImage(id).SetMask(downscale(mask, int(griditemsize)))
+			showcurrenttags()
+		})
+	})
+
+	crop := widget.NewButtonWithIcon("", theme.ContentCutIcon(), func() {
+		id := currentselectedimageid
+		if id < 0 {
+			return
+		}
+		g.cropimage(&p, id, func(id int, crop *image.Rectangle, width, height int) {
+			p.loadedImage(id).SetCrop(crop, width, height)
+			showcurrenttags()
+		})
+	})
+
 	settings := widget.NewButtonWithIcon("", theme.SettingsIcon(), func() {
 		colslabel := widget.NewLabel("")
 		cols := widget.NewSlider(1, 12)
@@ -323,17 +768,25 @@ func (g *gui) projectview(p projectStructure) fyne.CanvasObject {
 				alltagslist.SetColumns(defaultcolumns)
 				g.a.Preferences().SetInt("numcolums", defaultcolumns)
 				//
-				griditemsize = float32(imgsize.Value)
-				g.a.Preferences().SetInt("griditemsize", int(imgsize.Value))
-				imageviewer = container.NewGridWrap(fyne.NewSquareSize(griditemsize), imageviewer.Objects...)
-				imageviewercontainer.Content = imageviewer
-				imageviewercontainer.Refresh()
+				if griditemsize != float32(imgsize.Value) {
+					griditemsize = float32(imgsize.Value)
+					g.a.Preferences().SetInt("griditemsize", int(imgsize.Value))
+					imageviewer = container.NewGridWrap(fyne.NewSquareSize(griditemsize), imageviewer.Objects...)
+					imageviewercontainer.Content = imageviewer
+					imageviewercontainer.Refresh()
+					loadthumbnails()
+				}
 			}
 		}
 
 		// manual save
 		savenow := widget.NewButtonWithIcon("Save", theme.DocumentSaveIcon(), func() {
-			g.saveDialogErrorAndCallbackOnSuccess(&p, func() { dialog.ShowInformation("Success", "Data Saved", g.w) })
+			g.saveDialogErrorAndCallbackOnSuccess(&p, func(summary string) {
+				if summary == "" {
+					return // nothing was picked
+				}
+				dialog.ShowInformation("Success", "Data Saved\n"+summary, g.w)
+			})
 		})
 
 		d := dialog.NewCustomConfirm("Settings", "Ok", "Cancel", container.NewVBox(savenow, content, content2), cb, g.w)
@@ -344,14 +797,33 @@ func (g *gui) projectview(p projectStructure) fyne.CanvasObject {
 
 	imgvcont := container.NewVSplit(
 		imageviewercontainer,
-		imagelist,
+		container.NewBorder(container.NewBorder(nil, nil, nil, filterstatus, filter), nil, nil, nil, imagelist),
 	)
 	imgvcont.SetOffset(0.6)
+	tagsplit := container.NewVSplit(
+		container.NewVScroll(alltagslist),
+		container.NewAppTabs(
+			container.NewTabItem("Order", container.NewBorder(pinnedhint, nil, nil, nil, container.NewVScroll(tagorder))),
+			container.NewTabItem("Info", container.NewVScroll(info)),
+		),
+	)
+	tagsplit.SetOffset(0.7)
 	splitter := container.NewHSplit(
 		imgvcont,
-		container.NewBorder(container.NewBorder(nil, nil, nil, container.NewHBox(addtoall, settings), addtag), nil, nil, nil, container.NewVScroll(alltagslist)),
+		container.NewBorder(container.NewBorder(nil, nil, nil, container.NewHBox(addtoall, multiedit, undobutton, redobutton, managetags, duplicates, lint, buckets, paintmask, crop, problemsbutton, settings), addtag), historylabel, nil, nil, tagsplit),
 	)
 	splitter.SetOffset(0.6)
 
+	g.offerrecovery(&p, func() {
+		// restored crops and masks are drawn over the thumbnails again
+		stopthumbnails()
+		for _, ih := range p.loadedImages {
+			ih.SetCrop(nil, 0, 0)
+		}
+		loadthumbnails()
+		afteredit()
+	})
+	stopautosave = g.autosave(&p)
+
 	return splitter
 }
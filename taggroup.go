@@ -0,0 +1,85 @@
+package main
+
+import (
+	"slices"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// TagGroup is like widget.CheckGroup, but a tag can also be partially checked
+// for when only some of the selected images have it
+type TagGroup struct {
+	widget.BaseWidget
+	Options []string
+
+	checks []*widget.Check
+	grid   *fyne.Container
+
+	// called when the user checks or unchecks a tag, partial tags become checked
+	OnToggled func(tag string, checked bool)
+}
+
+var _ fyne.Widget = (*TagGroup)(nil)
+
+func NewTagGroup(options []string, toggled func(tag string, checked bool)) *TagGroup {
+	tg := &TagGroup{
+		grid:      container.NewGridWithColumns(1),
+		OnToggled: toggled,
+	}
+	tg.ExtendBaseWidget(tg)
+	for _, option := range options {
+		tg.Append(option)
+	}
+	return tg
+}
+
+func (tg *TagGroup) SetColumns(columns int) {
+	tg.grid.Layout = layout.NewGridLayout(max(columns, 1))
+	tg.grid.Refresh()
+}
+
+// Append adds a new option to the end if it is not there yet
+func (tg *TagGroup) Append(option string) {
+	if slices.Contains(tg.Options, option) {
+		return
+	}
+	tg.Options = append(tg.Options, option)
+
+	var check *widget.Check
+	check = widget.NewCheck(option, func(b bool) {
+		if tg.OnToggled != nil {
+			tg.OnToggled(check.Text, b)
+		}
+	})
+	tg.checks = append(tg.checks, check)
+	tg.grid.Add(check)
+}
+
+// SetOptions replaces all options
+func (tg *TagGroup) SetOptions(options []string) {
+	tg.Options = nil
+	tg.checks = nil
+	tg.grid.RemoveAll()
+	for _, option := range options {
+		tg.Append(option)
+	}
+}
+
+// SetSelected takes how many of total images have each tag,
+// the ones all of them have are checked and the ones only some have are partially checked.
+// this does not call OnToggled.
+func (tg *TagGroup) SetSelected(counts map[string]int, total int) {
+	for _, check := range tg.checks {
+		count := counts[check.Text]
+		check.Checked = total > 0 && count == total
+		check.Partial = count > 0 && count < total
+		check.Refresh()
+	}
+}
+
+func (tg *TagGroup) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(tg.grid)
+}
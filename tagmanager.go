@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"biehdc.priv.aidatasetmanager/dataset"
+)
+
+// managetags renames, merges and deletes tags across the whole dataset.
+// changed is called after every applied edit.
+func (g *gui) managetags(p *projectStructure, changed func()) {
+	var counts map[string]int
+	var alltags, shown []string
+	selected := make(map[string]struct{})
+
+	search := widget.NewEntry()
+	search.SetPlaceHolder("Search")
+
+	var taglist *widget.List
+	refilter := func() {
+		query := strings.ToLower(strings.TrimSpace(search.Text))
+		shown = shown[:0]
+		for _, tag := range alltags {
+			if strings.Contains(strings.ToLower(tag), query) {
+				shown = append(shown, tag)
+			}
+		}
+		taglist.Refresh()
+	}
+	reload := func() {
+		counts = p.CountTags()
+		alltags = p.CollectTags()
+		for tag := range selected {
+			if counts[tag] < 1 {
+				delete(selected, tag)
+			}
+		}
+		refilter()
+	}
+	search.OnChanged = func(string) { refilter() }
+
+	taglist = widget.NewList(
+		// length
+		func() int { return len(shown) },
+		// create
+		func() fyne.CanvasObject {
+			return widget.NewLabel("averagetagname.len")
+		},
+		// update
+		func(lii widget.ListItemID, co fyne.CanvasObject) {
+			label, ok := co.(*widget.Label)
+			if !ok {
+				return
+			}
+
+			tag := shown[lii]
+			if _, isSelected := selected[tag]; isSelected {
+				label.Importance = widget.SuccessImportance
+			} else {
+				label.Importance = widget.MediumImportance
+			}
+
+			label.SetText(fmt.Sprintf("%s (%d)", tag, counts[tag]))
+		},
+	)
+	taglist.OnSelected = func(id widget.ListItemID) {
+		tag := shown[id]
+		if _, isSelected := selected[tag]; isSelected {
+			delete(selected, tag)
+		} else {
+			selected[tag] = struct{}{}
+		}
+		taglist.Unselect(id)
+		taglist.RefreshItem(id)
+	}
+
+	// apply shows which images would change and only then does it
+	apply := func(name string, from []string, change func(e *dataset.Entry) error) {
+		affected := p.WithAnyTag(from)
+		if len(affected) < 1 {
+			return
+		}
+
+		preview := widget.NewList(
+			func() int { return len(affected) },
+			func() fyne.CanvasObject { return widget.NewLabel("averagefilename.len") },
+			func(lii widget.ListItemID, co fyne.CanvasObject) {
+				co.(*widget.Label).SetText(p.Entries[affected[lii]].Name())
+			},
+		)
+		content := container.NewBorder(
+			widget.NewLabel(fmt.Sprintf("%s\nThis changes %d images:", name, len(affected))), nil, nil, nil,
+			preview,
+		)
+
+		d := dialog.NewCustomConfirm("Apply", "Apply", "Cancel", content, func(b bool) {
+			if !b {
+				return
+			}
+			_, err := p.history.DoAll(name, change)
+			if err != nil {
+				dialog.ShowError(err, g.w)
+			}
+			reload()
+			changed()
+		}, g.w)
+		d.Show()
+		d.Resize(fyne.NewSize(d.MinSize().Width*2, d.MinSize().Height+300))
+	}
+
+	newname := widget.NewEntry()
+	newname.SetPlaceHolder("New name")
+	newname.TextStyle = fyne.TextStyle{Monospace: true}
+
+	renamebutton := widget.NewButtonWithIcon("Rename / Merge", theme.DocumentCreateIcon(), func() {
+		from := slices.Sorted(maps.Keys(selected))
+		if len(from) < 1 {
+			return
+		}
+		to, err := dataset.NormaliseTag(newname.Text)
+		if err != nil {
+			dialog.ShowError(err, g.w)
+			return
+		}
+
+		var name string
+		if len(from) == 1 {
+			name = fmt.Sprintf("rename %q to %q", from[0], to)
+		} else {
+			name = fmt.Sprintf("merge %s into %q", quotetags(from), to)
+		}
+		apply(name, from, func(e *dataset.Entry) error {
+			return e.ReplaceTags(from, to)
+		})
+	})
+
+	deletebutton := widget.NewButtonWithIcon("Delete", theme.DeleteIcon(), func() {
+		from := slices.Sorted(maps.Keys(selected))
+		if len(from) < 1 {
+			return
+		}
+		apply(fmt.Sprintf("delete %s", quotetags(from)), from, func(e *dataset.Entry) error {
+			e.RemoveTags(from)
+			return nil
+		})
+	})
+
+	clearbutton := widget.NewButtonWithIcon("", theme.ContentClearIcon(), func() {
+		clear(selected)
+		taglist.Refresh()
+	})
+
+	reload()
+
+	content := container.NewBorder(
+		container.NewBorder(nil, nil, nil, clearbutton, search),
+		container.NewBorder(nil, nil, nil, container.NewHBox(renamebutton, deletebutton), newname),
+		nil, nil,
+		taglist,
+	)
+	d := dialog.NewCustom("Manage Tags", "Close", content, g.w)
+	d.Show()
+	d.Resize(g.w.Canvas().Size().Subtract(fyne.NewSquareSize(100)))
+}
+
+func quotetags(tags []string) string {
+	quoted := make([]string, len(tags))
+	for i, tag := range tags {
+		quoted[i] = fmt.Sprintf("%q", tag)
+	}
+	return strings.Join(quoted, ", ")
+}
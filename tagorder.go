@@ -0,0 +1,134 @@
+package main
+
+import (
+	"slices"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// TagOrder lists the tags of one image top to bottom,
+// they can be dragged into another order and pinned for the whole project
+type TagOrder struct {
+	widget.BaseWidget
+
+	tags []string
+	rows []*tagorderrow
+	box  *fyne.Container
+
+	// called after a drag changed the order
+	OnReordered func(tags []string)
+	// called when the pin button of a tag is pressed
+	OnPinToggled func(tag string)
+}
+
+var _ fyne.Widget = (*TagOrder)(nil)
+
+func NewTagOrder(reordered func(tags []string), pintoggled func(tag string)) *TagOrder {
+	to := &TagOrder{
+		box:          container.NewVBox(),
+		OnReordered:  reordered,
+		OnPinToggled: pintoggled,
+	}
+	to.ExtendBaseWidget(to)
+	return to
+}
+
+// SetTags shows the tags in their order and marks the pinned ones
+func (to *TagOrder) SetTags(tags, pinned []string) {
+	to.tags = slices.Clone(tags)
+	to.rows = to.rows[:0]
+	to.box.RemoveAll()
+	for _, tag := range to.tags {
+		row := newtagorderrow(to, tag, slices.Contains(pinned, tag))
+		to.rows = append(to.rows, row)
+		to.box.Add(row)
+	}
+}
+
+// swap moves the row at i one step by dir while it is dragged
+func (to *TagOrder) swap(i, dir int) {
+	j := i + dir
+	to.tags[i], to.tags[j] = to.tags[j], to.tags[i]
+	to.rows[i], to.rows[j] = to.rows[j], to.rows[i]
+	to.box.Objects[i], to.box.Objects[j] = to.box.Objects[j], to.box.Objects[i]
+	to.box.Refresh()
+}
+
+func (to *TagOrder) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(to.box)
+}
+
+type tagorderrow struct {
+	widget.BaseWidget
+	to    *TagOrder
+	tag   string
+	label *widget.Label
+	pin   *widget.Button
+
+	// how far it was dragged since it last moved
+	offset float32
+	// the order when the drag started
+	before []string
+}
+
+var _ fyne.Draggable = (*tagorderrow)(nil)
+
+func newtagorderrow(to *TagOrder, tag string, pinned bool) *tagorderrow {
+	r := &tagorderrow{to: to, tag: tag, label: widget.NewLabel(tag)}
+	r.label.Truncation = fyne.TextTruncateEllipsis
+	r.pin = widget.NewButtonWithIcon("", theme.RadioButtonIcon(), func() {
+		if to.OnPinToggled != nil {
+			to.OnPinToggled(r.tag)
+		}
+	})
+	if pinned {
+		r.pin.Icon = theme.RadioButtonCheckedIcon()
+		r.pin.Importance = widget.HighImportance
+		r.label.TextStyle = fyne.TextStyle{Bold: true}
+	}
+	r.ExtendBaseWidget(r)
+	return r
+}
+
+func (r *tagorderrow) Dragged(ev *fyne.DragEvent) {
+	if r.before == nil {
+		r.before = slices.Clone(r.to.tags)
+		r.label.Importance = widget.HighImportance
+		r.label.Refresh()
+	}
+
+	// a row moves once it was dragged over half of its neighbour
+	step := r.Size().Height + theme.Padding()
+	r.offset += ev.Dragged.DY
+	i := slices.Index(r.to.rows, r)
+	for r.offset > step/2 && i < len(r.to.rows)-1 {
+		r.to.swap(i, 1)
+		r.offset -= step
+		i++
+	}
+	for r.offset < -step/2 && i > 0 {
+		r.to.swap(i, -1)
+		r.offset += step
+		i--
+	}
+}
+
+func (r *tagorderrow) DragEnd() {
+	before := r.before
+	r.before = nil
+	r.offset = 0
+	r.label.Importance = widget.MediumImportance
+	r.label.Refresh()
+
+	if before != nil && !slices.Equal(before, r.to.tags) && r.to.OnReordered != nil {
+		r.to.OnReordered(slices.Clone(r.to.tags))
+	}
+}
+
+func (r *tagorderrow) CreateRenderer() fyne.WidgetRenderer {
+	handle := widget.NewIcon(theme.MenuIcon())
+	return widget.NewSimpleRenderer(container.NewBorder(nil, nil, handle, r.pin, r.label))
+}
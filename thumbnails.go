@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"golang.org/x/image/draw"
+
+	"fyne.io/fyne/v2"
+
+	"biehdc.priv.aidatasetmanager/dataset"
+)
+
+// thumbnailer decodes images in the background and keeps a disk cache of the results
+type thumbnailer struct {
+	size     int
+	cachedir string // empty if there is no cache
+}
+
+func newthumbnailer(size int) *thumbnailer {
+	t := &thumbnailer{size: size}
+
+	cache, err := os.UserCacheDir()
+	if err == nil {
+		t.cachedir = filepath.Join(cache, "aidatasetmanager", "thumbnails")
+		err = os.MkdirAll(t.cachedir, 0o755)
+	}
+	if err != nil {
+		fyne.LogError("thumbnail cache disabled", err)
+		t.cachedir = ""
+	}
+
+	return t
+}
+
+// loadall fetches the thumbnails for all paths with a bounded number of workers.
+// cb gets called from the workers as soon as a thumbnail is done, until ctx is cancelled.
+// the returned wait blocks until no cb is running anymore, call it after cancelling.
+func (t *thumbnailer) loadall(ctx context.Context, paths []string, cb func(path string, img image.Image, err error)) (wait func()) {
+	jobs := make(chan string)
+	go func() {
+		defer close(jobs)
+		for _, path := range paths {
+			select {
+			case jobs <- path:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range max(runtime.NumCPU()-1, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				img, err := t.thumbnail(path)
+				if ctx.Err() != nil {
+					return
+				}
+				cb(path, img, err)
+			}
+		}()
+	}
+	return wg.Wait
+}
+
+// thumbnail returns the cached thumbnail or makes a new one.
+// the full image is only around while it gets downscaled.
+func (t *thumbnailer) thumbnail(path string) (image.Image, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, &dataset.Error{Op: "read", Path: path, Err: err}
+	}
+
+	cachefile := t.cachepath(path, fi)
+	if cachefile != "" {
+		cached, err := decodeimagefile(cachefile)
+		if err == nil {
+			return cached, nil
+		}
+	}
+
+	img, err := decodeimagefile(path)
+	if err != nil {
+		return nil, err
+	}
+	thumb := downscale(img, t.size)
+
+	if cachefile != "" {
+		err = writecachefile(cachefile, thumb)
+		if err != nil {
+			fyne.LogError("could not cache thumbnail", err)
+		}
+	}
+
+	return thumb, nil
+}
+
+// cachepath is keyed by everything that changes the thumbnail
+func (t *thumbnailer) cachepath(path string, fi os.FileInfo) string {
+	if t.cachedir == "" {
+		return ""
+	}
+	key := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d\x00%d\x00%d", path, fi.ModTime().UnixNano(), fi.Size(), t.size)))
+	return filepath.Join(t.cachedir, hex.EncodeToString(key[:])+".png")
+}
+
+// writecachefile goes through a temp file so a half written thumbnail is never picked up
+func writecachefile(path string, img image.Image) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "thumb-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // noop after the rename
+
+	err = png.Encode(tmp, img)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func decodeimagefile(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, &dataset.Error{Op: "read", Path: path, Err: err}
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, &dataset.Error{Op: "decode", Path: path, Err: err}
+	}
+	return img, nil
+}
+
+// downscale fits the image into a maxside square, smaller images are left alone
+func downscale(img image.Image, maxside int) image.Image {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if w <= maxside && h <= maxside {
+		return img
+	}
+
+	neww, newh := calculateNewResolution(w, h, maxside)
+	dst := image.NewNRGBA(image.Rect(0, 0, max(neww, 1), max(newh, 1)))
+	draw.BiLinear.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return dst
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"path/filepath"
+	"sync/atomic"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// zoomcanvas shows an image at any zoom, dragging pans and the scroll wheel zooms around the pointer.
+// only the part that is visible is handed to the canvas, so big images stay quick.
+type zoomcanvas struct {
+	widget.BaseWidget
+	image *canvas.Image
+
+	full image.Image
+	// screen units per pixel, 0 until the first layout fits the image
+	zoom float32
+	// the pixel in the middle of the widget
+	centerx, centery float32
+
+	// called whenever the zoom changes
+	OnZoomed func(zoom float32)
+}
+
+var _ fyne.Draggable = (*zoomcanvas)(nil)
+var _ fyne.Scrollable = (*zoomcanvas)(nil)
+var _ fyne.DoubleTappable = (*zoomcanvas)(nil)
+
+type subimager interface {
+	SubImage(r image.Rectangle) image.Image
+}
+
+func newzoomcanvas() *zoomcanvas {
+	zc := &zoomcanvas{image: canvas.NewImageFromResource(theme.FileImageIcon())}
+	zc.image.FillMode = canvas.ImageFillContain
+	zc.ExtendBaseWidget(zc)
+	return zc
+}
+
+// SetImage shows img fitted into the widget
+func (zc *zoomcanvas) SetImage(img image.Image) {
+	if _, ok := img.(subimager); !ok {
+		// every decoder in use returns one, this is just in case
+		rgba := image.NewNRGBA(img.Bounds())
+		draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+		img = rgba
+	}
+	zc.full = img
+	zc.image.Resource = nil
+	zc.image.FillMode = canvas.ImageFillStretch
+	zc.Fit()
+}
+
+// SetResource shows an icon instead of an image
+func (zc *zoomcanvas) SetResource(res fyne.Resource) {
+	zc.full = nil
+	zc.image.Image = nil
+	zc.image.Resource = res
+	zc.image.FillMode = canvas.ImageFillContain
+	zc.Refresh()
+}
+
+// Fit zooms so the whole image is visible
+func (zc *zoomcanvas) Fit() {
+	zc.zoom = 0
+	zc.Refresh()
+}
+
+// SetZoom zooms around the middle of the widget
+func (zc *zoomcanvas) SetZoom(zoom float32) {
+	size := zc.Size()
+	zc.zoomat(zoom, fyne.NewPos(size.Width/2, size.Height/2))
+}
+
+// Zoom is the current zoom, 1 is one pixel per screen unit
+func (zc *zoomcanvas) Zoom() float32 {
+	return zc.zoom
+}
+
+// zoomat changes the zoom while the pixel under pos stays where it is
+func (zc *zoomcanvas) zoomat(zoom float32, pos fyne.Position) {
+	if zc.full == nil || zc.zoom == 0 {
+		return
+	}
+	zoom = max(0.01, min(zoom, 64))
+	size := zc.Size()
+	px := zc.centerx + (pos.X-size.Width/2)/zc.zoom
+	py := zc.centery + (pos.Y-size.Height/2)/zc.zoom
+	zc.zoom = zoom
+	zc.centerx = px - (pos.X-size.Width/2)/zoom
+	zc.centery = py - (pos.Y-size.Height/2)/zoom
+	zc.Refresh()
+}
+
+func (zc *zoomcanvas) Scrolled(ev *fyne.ScrollEvent) {
+	zc.zoomat(zc.zoom*float32(math.Pow(1.1, float64(ev.Scrolled.DY)/10)), ev.Position)
+}
+
+func (zc *zoomcanvas) Dragged(ev *fyne.DragEvent) {
+	if zc.full == nil || zc.zoom == 0 {
+		return
+	}
+	zc.centerx -= ev.Dragged.DX / zc.zoom
+	zc.centery -= ev.Dragged.DY / zc.zoom
+	zc.Refresh()
+}
+
+func (zc *zoomcanvas) DragEnd() {}
+
+// DoubleTapped switches between fitting the image and showing it at full size where it was tapped
+func (zc *zoomcanvas) DoubleTapped(ev *fyne.PointEvent) {
+	if zc.full == nil {
+		return
+	}
+	if zc.zoom == 1 {
+		zc.Fit()
+		return
+	}
+	zc.zoomat(1, ev.Position)
+}
+
+func (zc *zoomcanvas) CreateRenderer() fyne.WidgetRenderer {
+	return &zoomcanvasRenderer{zc: zc}
+}
+
+type zoomcanvasRenderer struct {
+	zc *zoomcanvas
+}
+
+func (r *zoomcanvasRenderer) Destroy() {}
+
+func (r *zoomcanvasRenderer) Objects() []fyne.CanvasObject {
+	return []fyne.CanvasObject{r.zc.image}
+}
+
+func (r *zoomcanvasRenderer) Layout(size fyne.Size) {
+	zc := r.zc
+	if zc.full == nil {
+		zc.image.Move(fyne.NewPos(0, 0))
+		zc.image.Resize(size)
+		return
+	}
+	b := zc.full.Bounds()
+	w, h := float32(b.Dx()), float32(b.Dy())
+	if zc.zoom == 0 && size.Width > 0 && size.Height > 0 {
+		zc.zoom = min(size.Width/w, size.Height/h)
+		zc.centerx, zc.centery = w/2, h/2
+		if zc.OnZoomed != nil {
+			zc.OnZoomed(zc.zoom)
+		}
+	}
+	if zc.zoom == 0 {
+		return
+	}
+	// panning stops once an edge of the image reaches the middle
+	zc.centerx = max(0, min(zc.centerx, w))
+	zc.centery = max(0, min(zc.centery, h))
+
+	// the pixels that are visible, a pixel more on each side so the edges are not cut off
+	left := zc.centerx - size.Width/2/zc.zoom
+	top := zc.centery - size.Height/2/zc.zoom
+	visible := image.Rect(
+		int(math.Floor(float64(left)))-1, int(math.Floor(float64(top)))-1,
+		int(math.Ceil(float64(left+size.Width/zc.zoom)))+1, int(math.Ceil(float64(top+size.Height/zc.zoom)))+1,
+	).Intersect(image.Rect(0, 0, b.Dx(), b.Dy()))
+	if visible.Empty() {
+		zc.image.Hide()
+		return
+	}
+	zc.image.Show()
+	zc.image.Image = zc.full.(subimager).SubImage(visible.Add(b.Min))
+	// single pixels are what is looked for when zoomed in that far
+	if zc.zoom >= 2 {
+		zc.image.ScaleMode = canvas.ImageScalePixels
+	} else {
+		zc.image.ScaleMode = canvas.ImageScaleSmooth
+	}
+	zc.image.Move(fyne.NewPos((float32(visible.Min.X)-left)*zc.zoom, (float32(visible.Min.Y)-top)*zc.zoom))
+	zc.image.Resize(fyne.NewSize(float32(visible.Dx())*zc.zoom, float32(visible.Dy())*zc.zoom))
+	zc.image.Refresh()
+}
+
+func (r *zoomcanvasRenderer) MinSize() fyne.Size {
+	return fyne.NewSquareSize(theme.IconInlineSize() * 4)
+}
+
+func (r *zoomcanvasRenderer) Refresh() {
+	r.Layout(r.zc.Size())
+	if r.zc.OnZoomed != nil {
+		r.zc.OnZoomed(r.zc.zoom)
+	}
+}
+
+// viewer is a window that shows images at full size.
+// it stays open while other images are shown in it.
+type viewer struct {
+	w    fyne.Window
+	zc   *zoomcanvas
+	name *widget.Label
+	// counts up with every open, decoding an older image is dropped
+	generation atomic.Uint64
+
+	// step goes delta images forward or back and returns false if there is nothing there,
+	// nil if the viewer only shows one image
+	step func(delta int) bool
+}
+
+func (g *gui) newviewer(step func(delta int) bool) *viewer {
+	v := &viewer{
+		w:    g.a.NewWindow("Viewer"),
+		zc:   newzoomcanvas(),
+		name: widget.NewLabel(""),
+		step: step,
+	}
+	v.name.Truncation = fyne.TextTruncateEllipsis
+
+	zoomlabel := widget.NewLabel("")
+	v.zc.OnZoomed = func(zoom float32) {
+		zoomlabel.SetText(fmt.Sprintf("%.0f%%", zoom*100))
+	}
+	fit := widget.NewButtonWithIcon("", theme.ZoomFitIcon(), v.zc.Fit)
+	zoomin := widget.NewButtonWithIcon("", theme.ZoomInIcon(), func() { v.zc.SetZoom(v.zc.Zoom() * 1.5) })
+	zoomout := widget.NewButtonWithIcon("", theme.ZoomOutIcon(), func() { v.zc.SetZoom(v.zc.Zoom() / 1.5) })
+	fullsize := widget.NewButton("1:1", func() { v.zc.SetZoom(1) })
+	tools := container.NewHBox(zoomout, zoomlabel, zoomin, fit, fullsize)
+	if step != nil {
+		prev := widget.NewButtonWithIcon("", theme.NavigateBackIcon(), func() { step(-1) })
+		next := widget.NewButtonWithIcon("", theme.NavigateNextIcon(), func() { step(1) })
+		tools = container.NewHBox(prev, next, tools)
+	}
+
+	v.w.Canvas().SetOnTypedKey(func(ev *fyne.KeyEvent) {
+		switch ev.Name {
+		case fyne.KeyLeft, fyne.KeyPageUp, fyne.KeyUp:
+			if step != nil {
+				step(-1)
+			}
+		case fyne.KeyRight, fyne.KeyPageDown, fyne.KeyDown, fyne.KeySpace:
+			if step != nil {
+				step(1)
+			}
+		case fyne.KeyPlus, fyne.KeyEqual:
+			v.zc.SetZoom(v.zc.Zoom() * 1.5)
+		case fyne.KeyMinus:
+			v.zc.SetZoom(v.zc.Zoom() / 1.5)
+		case fyne.Key0:
+			v.zc.Fit()
+		case fyne.Key1:
+			v.zc.SetZoom(1)
+		case fyne.KeyEscape:
+			v.w.Close()
+		}
+	})
+
+	v.w.SetContent(container.NewBorder(container.NewBorder(nil, nil, nil, tools, v.name), nil, nil, nil, v.zc))
+	v.w.Resize(fyne.NewSize(1024, 768))
+	return v
+}
+
+// open decodes the image at path and shows it, the full image is dropped again with the next one
+func (v *viewer) open(path string) {
+	generation := v.generation.Add(1)
+	v.w.SetTitle(filepath.Base(path))
+	v.name.SetText(path)
+	v.zc.SetResource(theme.FileImageIcon())
+	v.w.Show()
+	v.w.RequestFocus()
+
+	go func() {
+		img, err := decodeimagefile(path)
+		if v.generation.Load() != generation {
+			return // stepped on in the meantime
+		}
+		if err != nil {
+			v.zc.SetResource(theme.BrokenImageIcon())
+			dialog.ShowError(err, v.w)
+			return
+		}
+		v.zc.SetImage(img)
+	}()
+}
+
+// showoriginal opens the full size image in its own window
+func (g *gui) showoriginal(path string) {
+	g.newviewer(nil).open(path)
+}